@@ -0,0 +1,28 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"context"
+)
+
+// Allocator allocates the memory argon2 uses for its block matrix. See Config.Allocator.
+//
+// The memory returned by Allocate() may be passed to C and thus MUST NOT contain Go pointers.
+// It must be aligned to at least 8 bytes. Implementations must be safe for concurrent use.
+type Allocator interface {
+	// Allocate returns a slice of exactly `size` bytes. See Config.MemorySize().
+	Allocate(size int) ([]byte, error)
+
+	// Free releases memory previously returned by Allocate().
+	// The memory has already been erased when this method is called.
+	Free(b []byte) error
+}
+
+// contextAllocator is implemented by allocators which may block until memory may be allocated,
+// like the one used by Hasher. Config prefers allocateContext() over Allocate() if available.
+type contextAllocator interface {
+	allocateContext(ctx context.Context, size int) ([]byte, error)
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build cgo
+
+package argon2
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// MallocAllocator allocates memory using malloc() and free().
+// This is equivalent to not using an Allocator at all.
+type MallocAllocator struct{}
+
+// Allocate implements the Allocator interface.
+func (MallocAllocator) Allocate(size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, nil
+	}
+
+	ptr := C.malloc(C.size_t(size))
+	if ptr == nil {
+		return nil, ErrMemoryAllocationError
+	}
+
+	return unsafe.Slice((*byte)(ptr), size), nil
+}
+
+// Free implements the Allocator interface.
+func (MallocAllocator) Free(b []byte) error {
+	if cap(b) > 0 {
+		C.free(unsafe.Pointer(&b[:1][0]))
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !cgo
+
+package argon2
+
+// MallocAllocator allocates memory on the Go heap, as malloc() is unavailable without cgo.
+// This is equivalent to not using an Allocator at all.
+type MallocAllocator struct{}
+
+// Allocate implements the Allocator interface.
+func (MallocAllocator) Allocate(size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, nil
+	}
+	return make([]byte, size), nil
+}
+
+// Free implements the Allocator interface.
+func (MallocAllocator) Free(b []byte) error {
+	return nil
+}
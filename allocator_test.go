@@ -0,0 +1,75 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+)
+
+type countingAllocator struct {
+	MallocAllocator
+	allocated int64
+	size      int64
+}
+
+func (a *countingAllocator) Allocate(size int) ([]byte, error) {
+	atomic.AddInt64(&a.allocated, 1)
+	atomic.StoreInt64(&a.size, int64(size))
+	return a.MallocAllocator.Allocate(size)
+}
+
+func (a *countingAllocator) Free(b []byte) error {
+	atomic.AddInt64(&a.allocated, -1)
+	return a.MallocAllocator.Free(b)
+}
+
+func testAllocator(t *testing.T, a Allocator) {
+	cfg := config
+	cfg.Allocator = a
+
+	r, err := cfg.Hash(password, salt)
+	mustBeFalsey(t, "err", err)
+
+	if !bytes.Equal(r.Hash, expectedHash) {
+		t.Error("hashes do not match")
+	}
+}
+
+func TestMallocAllocator(t *testing.T) {
+	testAllocator(t, MallocAllocator{})
+}
+
+func TestCountingAllocator(t *testing.T) {
+	a := &countingAllocator{}
+	testAllocator(t, a)
+
+	if a.allocated != 0 {
+		t.Errorf("%d allocations were not freed", a.allocated)
+	}
+	if uint64(a.size) != config.MemorySize() {
+		t.Errorf("expected an allocation of %d bytes, got: %d", config.MemorySize(), a.size)
+	}
+}
+
+func TestMemorySize(t *testing.T) {
+	for _, tc := range []struct {
+		memoryCost  uint32
+		parallelism uint32
+		expected    uint64
+	}{
+		{32 * 1024, 1, 32 * 1024 * 1024},
+		{1, 1, 8 * 1024},
+		{1, 4, 32 * 1024},
+		{1023, 4, 1008 * 1024},
+	} {
+		cfg := Config{MemoryCost: tc.memoryCost, Parallelism: tc.parallelism}
+
+		if size := cfg.MemorySize(); size != tc.expected {
+			t.Errorf("m=%d,p=%d: expected %d, got: %d", tc.memoryCost, tc.parallelism, tc.expected, size)
+		}
+	}
+}
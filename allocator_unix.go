@@ -0,0 +1,85 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package argon2
+
+import (
+	"os"
+	"syscall"
+)
+
+// MmapAllocator allocates memory using anonymous, private memory mappings.
+//
+// Unlike malloc() the memory is always returned to the operating system once it's freed.
+type MmapAllocator struct{}
+
+// Allocate implements the Allocator interface.
+func (MmapAllocator) Allocate(size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, nil
+	}
+	return syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+}
+
+// Free implements the Allocator interface.
+func (MmapAllocator) Free(b []byte) error {
+	if b == nil {
+		return nil
+	}
+	return syscall.Munmap(b)
+}
+
+// FileAllocator allocates memory using shared memory mappings of temporary files.
+//
+// This allows you to use more memory than is physically available, which can be useful
+// for very large, offline key derivations. The temporary files are deleted immediately
+// after being created and are thus removed by the operating system once the memory is freed.
+//
+// WARNING: The memory contains blocks derived from the password, which the kernel
+// writes back to the file system. They may persist on disk even after the memory
+// has been erased and freed, for instance if the process crashes or the file
+// system doesn't discard the blocks of deleted files. Only use FileAllocator
+// with a Dir on an encrypted or memory backed file system (e.g. tmpfs).
+// A private mapping can't be used, as it would be backed by swap instead.
+type FileAllocator struct {
+	// Dir specifies the directory in which temporary files are created.
+	// If empty, os.TempDir() is used.
+	Dir string
+}
+
+// Allocate implements the Allocator interface.
+func (a FileAllocator) Allocate(size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, nil
+	}
+
+	f, err := os.CreateTemp(a.Dir, "argon2-")
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	err = os.Remove(f.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	err = f.Truncate(int64(size))
+	if err != nil {
+		return nil, err
+	}
+
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// Free implements the Allocator interface.
+func (FileAllocator) Free(b []byte) error {
+	if b == nil {
+		return nil
+	}
+	return syscall.Munmap(b)
+}
@@ -0,0 +1,19 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package argon2
+
+import (
+	"testing"
+)
+
+func TestMmapAllocator(t *testing.T) {
+	testAllocator(t, MmapAllocator{})
+}
+
+func TestFileAllocator(t *testing.T) {
+	testAllocator(t, FileAllocator{Dir: t.TempDir()})
+}
@@ -1,3 +1,5 @@
+//go:build cgo
+
 /*
  * Argon2 reference source code package - reference C implementations
  *
@@ -89,6 +91,8 @@ int argon2_ctx(argon2_context *context, argon2_type type) {
     result = fill_memory_blocks(&instance);
 
     if (ARGON2_OK != result) {
+        free_memory(context, (uint8_t *)instance.memory,
+                    instance.memory_blocks, sizeof(block));
         return result;
     }
     /* 5. Finalization */
@@ -147,6 +151,7 @@ int argon2_hash(const uint32_t t_cost, const uint32_t m_cost,
     context.free_cbk = NULL;
     context.flags = ARGON2_DEFAULT_FLAGS;
     context.version = version;
+    context.abort_flag = NULL;
 
     result = argon2_ctx(&context, type);
 
@@ -439,6 +444,8 @@ const char *argon2_error_message(int error_code) {
         return "Some of encoded parameters are too long or too short";
     case ARGON2_VERIFY_MISMATCH:
         return "The password does not match the supplied hash";
+    case ARGON2_ABORTED:
+        return "The computation was aborted";
     default:
         return "Unknown error code";
     }
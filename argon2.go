@@ -7,64 +7,39 @@
 // Password Hashing Competition (PHC).
 package argon2
 
-/*
-#include <stdint.h>
-
-#include "argon2.h"
-#include "core.h"
-
-// This is structurally the same as the Config struct below
-typedef struct bindings_argon2_config {
-	uint32_t HashLength;
-	uint32_t SaltLength;
-	uint32_t TimeCost;
-	uint32_t MemoryCost;
-	uint32_t Parallelism;
-	uint32_t Mode;
-	uint32_t Version;
-} bindings_argon2_config;
-
-// A simplified version of argon2_hash()
-int bindings_argon2_hash(const bindings_argon2_config* cfg, void* pwd, const uint32_t pwdlen, void* salt, const uint32_t saltlen, void* hash, const uint32_t hashlen) {
-	argon2_context c = {
-		.out = hash,
-		.outlen = hashlen,
-		.pwd = pwd,
-		.pwdlen = pwdlen,
-		.salt = salt,
-		.saltlen = saltlen,
-		.secret = NULL,
-		.secretlen = 0,
-		.ad = NULL,
-		.adlen = 0,
-		.t_cost = cfg->TimeCost,
-		.m_cost = cfg->MemoryCost,
-		.lanes = cfg->Parallelism,
-		.threads = cfg->Parallelism,
-		.version = cfg->Version,
-		.allocate_cbk = NULL,
-		.free_cbk = NULL,
-		.flags = ARGON2_DEFAULT_FLAGS,
-	};
-
-	const int rc = argon2_ctx(&c, cfg->Mode);
-
-	if (rc != ARGON2_OK) {
-		clear_internal_memory(hash, hashlen);
-	}
-
-	return rc;
-}
-*/
-import "C"
-
 import (
+	"context"
 	"crypto/rand"
 	"crypto/subtle"
+	"errors"
+	"io"
 	"runtime"
-	"unsafe"
+	"sync"
 )
 
+// Constants and input parameter restrictions as defined in argon2.h and core.h.
+const (
+	syncPoints      = 4
+	blockSize       = 1024
+	minOutLength    = 4
+	maxOutLength    = 0xFFFFFFFF
+	minSaltLength   = 8
+	maxSaltLength   = 0xFFFFFFFF
+	maxPwdLength    = 0xFFFFFFFF
+	maxSecretLength = 0xFFFFFFFF
+	maxAdLength     = 0xFFFFFFFF
+	minTime         = 1
+	maxTime         = 0xFFFFFFFF
+	minLanes        = 1
+	maxLanes        = 0xFFFFFF
+	minThreads      = 1
+	maxThreads      = 0xFFFFFF
+	minMemory       = 2 * syncPoints
+)
+
+// The largest amount of memory that can be requested from an Allocator.
+const maxAllocationSize = uint64(^uint(0) >> 1)
+
 // Mode exists for type check purposes. See Config.
 type Mode uint32
 
@@ -73,19 +48,19 @@ const (
 	// which makes it highly resistant against GPU cracking attacks and
 	// suitable for applications with no (!) threats from
 	// side-channel timing attacks (eg. cryptocurrencies).
-	ModeArgon2d Mode = C.Argon2_d
+	ModeArgon2d Mode = 0
 
 	// ModeArgon2i uses data-independent memory access, which is
 	// preferred for password hashing and password-based key derivation
 	// (e.g. hard drive encryption), but it's slower as it makes
 	// more passes over the memory to protect from TMTO attacks.
-	ModeArgon2i Mode = C.Argon2_i
+	ModeArgon2i Mode = 1
 
 	// ModeArgon2id is a hybrid of Argon2i and Argon2d, using a
 	// combination of data-depending and data-independent memory accesses,
 	// which gives some of Argon2i's resistance to side-channel cache timing
 	// attacks and much of Argon2d's resistance to GPU cracking attacks.
-	ModeArgon2id Mode = C.Argon2_id
+	ModeArgon2id Mode = 2
 )
 
 // String simply maps a ModeArgon{d,i,id} constant to a "Argon{d,i,id}" string
@@ -110,10 +85,10 @@ type Version uint32
 
 const (
 	// Version10 of the Argon2 algorithm. Deprecated: Use Version13 instead.
-	Version10 Version = C.ARGON2_VERSION_10
+	Version10 Version = 0x10
 
 	// Version13 of the Argon2 algorithm. Recommended.
-	Version13 Version = C.ARGON2_VERSION_13
+	Version13 Version = 0x13
 )
 
 // String simply maps a Version{10,13} constant to a "{10,13}" string
@@ -129,7 +104,24 @@ func (v Version) String() string {
 	}
 }
 
-// NOTE: Keep `Config` in sync with the C code at the beginning of this file.
+// Flags specifies additional behavior of the Hash*() and Verify*() methods.
+//
+// See Config.
+type Flags uint32
+
+const (
+	// FlagClearPassword erases the password passed to the Hash*() methods
+	// using SecureZeroMemory() once the hash has been computed, even if an error occurred.
+	// The password MUST NOT be read-only memory, e.g. a []byte converted using unsafe from a string.
+	//
+	// The Config of the Raw returned by Hash*() has no Flags set. Raw.Verify*() thus only
+	// erase their inputs if the flags are set in Raw.Config explicitly.
+	FlagClearPassword Flags = 1 << 0
+
+	// FlagClearSecret works like FlagClearPassword, but erases the secret.
+	// The secrets of a Keyring are never erased, as only copies of them are passed on.
+	FlagClearSecret Flags = 1 << 1
+)
 
 // Config contains all configuration parameters for the Argon2 hash function.
 //
@@ -163,7 +155,8 @@ type Config struct {
 	// Must be > 0.
 	MemoryCost uint32
 
-	// Parallelism specifies the amount of threads to use.
+	// Parallelism specifies the number of lanes used by argon2,
+	// which also limits the amount of threads it can make use of.
 	//
 	// Must be > 0.
 	Parallelism uint32
@@ -176,8 +169,38 @@ type Config struct {
 
 	// Version specifies the argon2 version to be used.
 	Version Version
+
+	// Threads specifies the maximum amount of threads to use.
+	//
+	// If 0, min(Parallelism, GOMAXPROCS) threads are used.
+	// Values larger than Parallelism are capped to Parallelism.
+	// Unlike all other parameters, this does not affect the resulting hash.
+	Threads uint32
+
+	// Allocator specifies how the memory used by argon2 is allocated.
+	//
+	// If nil, the memory is allocated using malloc().
+	// Unlike all other parameters, this does not affect the resulting hash.
+	Allocator Allocator
+
+	// Rand specifies the entropy source used to generate salts of SaltLength bytes
+	// if no salt is passed to Hash() etc. This allows using a dedicated DRBG or
+	// a deterministic reader in tests. It must be safe for concurrent use,
+	// if the Config is used concurrently.
+	//
+	// If nil, crypto/rand.Reader is used.
+	Rand io.Reader
+
+	// Flags specifies whether the password and secret are erased after hashing.
+	// Unlike most other parameters, this does not affect the resulting hash.
+	//
+	// The *String() methods ignore FlagClearPassword, as strings are immutable.
+	Flags Flags
 }
 
+// ErrSaltShortRead is returned if Config.Rand returned fewer than SaltLength bytes.
+var ErrSaltShortRead = errors.New("argon2: entropy source returned fewer bytes than the salt length")
+
 // DefaultConfig returns a Config struct suitable for most servers.
 //
 // These default settings follow the recommendation from
@@ -185,6 +208,11 @@ type Config struct {
 // using ModeArgon2id, TimeCost of 1 and 32 MiB of memory,
 // which result in around 10-15ms of computation time.
 // (Tested on an i7 8700k and DDR4 @ 3200 MHz).
+//
+// The draft has since been published as RFC 9106, which recommends the
+// settings returned by RFC9106FirstConfig() and RFC9106SecondConfig() instead.
+// The OWASP*Config() functions return the settings recommended by OWASP.
+// Use Calibrate() to find the strongest settings for your own hardware.
 func DefaultConfig() Config {
 	return Config{
 		HashLength:  32,
@@ -199,64 +227,221 @@ func DefaultConfig() Config {
 
 // Hash takes a password and optionally a salt and returns an Argon2 hash.
 //
-// If salt is nil a appropriate salt of Config.SaltLength bytes is generated for you.
+// If salt is nil a appropriate salt of Config.SaltLength bytes is generated for you
+// using Config.Rand. ErrSaltTooShort is returned if SaltLength is less than 8.
 func (c *Config) Hash(pwd []byte, salt []byte) (*Raw, error) {
-	if pwd == nil {
-		return nil, ErrPwdTooShort
+	return c.HashWithSecret(pwd, salt, nil)
+}
+
+// HashWithSecret works like Hash(), but additionally keys the hash with `secret`
+// (also known as a "pepper"). The same secret must be passed to VerifyWithSecret().
+//
+// The secret is only read for the duration of the call and is never retained.
+// Intermediate state derived from it, like the BLAKE2b state of the initial hash,
+// is wiped before returning. `secret` itself is left untouched, unless
+// FlagClearSecret is set. If secret is nil or empty this is equivalent to calling Hash().
+func (c *Config) HashWithSecret(pwd []byte, salt []byte, secret []byte) (*Raw, error) {
+	return c.HashWithData(pwd, salt, secret, nil)
+}
+
+// HashWithData works like HashWithSecret(), but additionally binds the hash to the
+// associated data `ad`, which can be used for domain separation (e.g. by passing a
+// tenant ID, user ID or purpose label). Both secret and ad may be nil.
+//
+// The associated data is stored in the resulting Raw struct and thus becomes part of
+// its encoded form. Use VerifyWithData() to verify a hash against the expected
+// associated data instead of the stored one.
+func (c *Config) HashWithData(pwd []byte, salt []byte, secret []byte, ad []byte) (*Raw, error) {
+	return c.hash(context.Background(), pwd, salt, secret, ad)
+}
+
+// HashContext works like Hash(), but aborts the computation as soon as `ctx` is done.
+//
+// Cancellation is checked in between the synchronization points of the algorithm,
+// after which the allocated memory is erased and freed and ctx.Err() is returned.
+func (c *Config) HashContext(ctx context.Context, pwd []byte, salt []byte) (*Raw, error) {
+	return c.hash(ctx, pwd, salt, nil, nil)
+}
+
+// HashInto works like Hash(), but writes the hash into `dst` instead of allocating
+// a Raw struct, which allows hashing without any allocations (besides the memory
+// matrix, see Config.Allocator and Hasher). `dst` must be exactly c.HashLength
+// bytes long and `salt` must not be nil, otherwise ErrOutPtrMismatch or ErrSaltTooShort
+// are returned respectively.
+func (c *Config) HashInto(dst []byte, pwd []byte, salt []byte) error {
+	if uint64(len(dst)) != uint64(c.HashLength) {
+		c.clearInputs(pwd, nil)
+		return ErrOutPtrMismatch
 	}
 
 	if salt == nil {
-		salt = make([]byte, c.SaltLength)
-		_, err := rand.Read(salt)
+		c.clearInputs(pwd, nil)
+		return ErrSaltTooShort
+	}
+
+	return c.hashInto(context.Background(), dst, pwd, salt, nil, nil)
+}
 
+func (c *Config) hash(ctx context.Context, pwd []byte, salt []byte, secret []byte, ad []byte) (*Raw, error) {
+	if salt == nil {
+		var err error
+		salt, err = c.generateSalt()
 		if err != nil {
+			c.clearInputs(pwd, secret)
 			return nil, err
 		}
 	}
 
-	defer runtime.KeepAlive(pwd)
-	defer runtime.KeepAlive(salt)
+	hash := make([]byte, c.HashLength)
+
+	err := c.hashInto(ctx, hash, pwd, salt, secret, ad)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ad) == 0 {
+		ad = nil
+	}
+
+	r := &Raw{
+		Config:         *c,
+		Salt:           salt,
+		Hash:           hash,
+		AssociatedData: ad,
+	}
+
+	// The flags only apply to hashing. Otherwise Raw.Verify*() would erase their inputs.
+	r.Config.Flags = 0
+	return r, nil
+}
+
+// generateSalt reads a salt of c.SaltLength bytes from c.Rand.
+func (c *Config) generateSalt() ([]byte, error) {
+	if c.SaltLength < minSaltLength {
+		return nil, ErrSaltTooShort
+	}
+
+	r := c.Rand
+	if r == nil {
+		r = rand.Reader
+	}
+
+	salt := make([]byte, c.SaltLength)
+	_, err := io.ReadFull(r, salt)
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, ErrSaltShortRead
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return salt, nil
+}
+
+// clearInputs erases `pwd` and `secret` depending on c.Flags.
+func (c *Config) clearInputs(pwd []byte, secret []byte) {
+	if c.Flags&FlagClearPassword != 0 {
+		SecureZeroMemory(pwd)
+	}
+	if c.Flags&FlagClearSecret != 0 {
+		SecureZeroMemory(secret)
+	}
+}
+
+func (c *Config) hashInto(ctx context.Context, out []byte, pwd []byte, salt []byte, secret []byte, ad []byte) (err error) {
+	defer c.clearInputs(pwd, secret)
+
+	if pwd == nil {
+		return ErrPwdTooShort
+	}
+
+	if uint64(len(pwd)) > maxPwdLength {
+		return ErrPwdTooLong
+	}
+
+	if uint64(len(salt)) > maxSaltLength {
+		return ErrSaltTooLong
+	}
+
+	if uint64(len(secret)) > maxSecretLength {
+		return ErrSecretTooLong
+	}
+
+	if uint64(len(ad)) > maxAdLength {
+		return ErrAdTooLong
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	size := c.MemorySize()
+	var mem []byte
+
+	// Allocators implementing contextAllocator reserve the memory budget themselves.
+	if _, ok := c.Allocator.(contextAllocator); !ok {
+		err = globalMemoryBudget.reserve(ctx, size)
+		if err != nil {
+			return err
+		}
+		defer globalMemoryBudget.release(size)
+	}
+
+	if c.Allocator != nil {
+		if size > maxAllocationSize {
+			return ErrMemoryTooMuch
+		}
 
-	pwdptr := unsafe.Pointer(nil)
-	pwdlen := C.uint32_t(len(pwd))
-	saltptr := unsafe.Pointer(nil)
-	saltlen := C.uint32_t(len(salt))
-	hashptr := unsafe.Pointer(nil)
-	hashlen := C.uint32_t(c.HashLength)
+		if a, ok := c.Allocator.(contextAllocator); ok {
+			mem, err = a.allocateContext(ctx, int(size))
+		} else {
+			mem, err = c.Allocator.Allocate(int(size))
+		}
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			ferr := c.Allocator.Free(mem)
+			if err == nil && ferr != nil {
+				err = ferr
+			}
+		}()
+	}
 
-	hash := make([]byte, hashlen)
+	return compute(ctx, c, out, pwd, salt, secret, ad, mem)
+}
 
-	if pwdlen > 0 {
-		pwdptr = unsafe.Pointer(&pwd[0])
+// threads returns the effective amount of threads to use. See Config.Threads.
+func (c *Config) threads() uint32 {
+	n := c.Threads
+	if n == 0 {
+		n = uint32(runtime.GOMAXPROCS(0))
 	}
 
-	if saltlen > 0 {
-		saltptr = unsafe.Pointer(&salt[0])
+	if n > c.Parallelism {
+		n = c.Parallelism
 	}
+	return n
+}
 
-	if hashlen > 0 {
-		hashptr = unsafe.Pointer(&hash[0])
+// MemorySize returns the amount of memory in bytes argon2 allocates for its block matrix.
+// This is MemoryCost KiB, rounded down to a multiple of 4*Parallelism KiB (but at least 8*Parallelism KiB).
+func (c *Config) MemorySize() uint64 {
+	if c.Parallelism == 0 {
+		return 0
 	}
 
-	rc := C.bindings_argon2_hash(
-		(*C.struct_bindings_argon2_config)(unsafe.Pointer(c)),
-		pwdptr,
-		pwdlen,
-		saltptr,
-		saltlen,
-		hashptr,
-		hashlen,
-	)
+	lanes := uint64(c.Parallelism)
+	blocks := uint64(c.MemoryCost)
 
-	if rc != C.ARGON2_OK {
-		return nil, Error(rc)
+	if blocks < 2*syncPoints*lanes {
+		blocks = 2 * syncPoints * lanes
 	}
 
-	return &Raw{
-		Config: *c,
-		Salt:   salt,
-		Hash:   hash,
-	}, nil
+	blocks -= blocks % (syncPoints * lanes)
+	return blocks * blockSize
 }
 
 // HashRaw is a helper function around Hash()
@@ -283,32 +468,175 @@ type Raw struct {
 	Config Config
 	Salt   []byte
 	Hash   []byte
+
+	// KeyID identifies the secret the hash was computed with. See Keyring.
+	// It's nil if no key identifier was used.
+	KeyID []byte
+
+	// AssociatedData contains the optional associated data passed to HashWithData().
+	// It's nil if no associated data was used.
+	AssociatedData []byte
+
+	// limits are checked before computing a hash if not nil. See Limits.Apply().
+	limits *Limits
 }
 
 // Verify returns true if `pwd` matches the hash in `raw` and otherwise false.
+// Like all Verify*() methods it only erases `pwd` if raw.Config.Flags say so.
 func (raw *Raw) Verify(pwd []byte) (bool, error) {
-	r, err := raw.Config.Hash(pwd, raw.Salt)
+	return raw.VerifyWithSecret(pwd, nil)
+}
+
+// VerifyWithSecret works like Verify(), but for hashes created using HashWithSecret().
+func (raw *Raw) VerifyWithSecret(pwd []byte, secret []byte) (bool, error) {
+	return raw.VerifyWithData(pwd, secret, raw.AssociatedData)
+}
+
+// VerifyWithData works like VerifyWithSecret(), but uses the expected associated data `ad`
+// instead of raw.AssociatedData. This ensures that a hash which was copied between
+// different domains (e.g. users or tenants) doesn't verify successfully.
+func (raw *Raw) VerifyWithData(pwd []byte, secret []byte, ad []byte) (bool, error) {
+	return raw.verify(context.Background(), pwd, secret, ad)
+}
+
+// VerifyContext works like Verify(), but aborts the computation as soon as `ctx` is done.
+// See Config.HashContext().
+func (raw *Raw) VerifyContext(ctx context.Context, pwd []byte) (bool, error) {
+	return raw.verify(ctx, pwd, nil, raw.AssociatedData)
+}
+
+// verifyBuffers contains the buffers verify() computes hashes of up to 64 bytes into.
+// A stack buffer can't be used, because slices passed to C always escape to the heap.
+var verifyBuffers = sync.Pool{
+	New: func() interface{} {
+		return new([64]byte)
+	},
+}
+
+func (raw *Raw) verify(ctx context.Context, pwd []byte, secret []byte, ad []byte) (bool, error) {
+	if raw.limits != nil {
+		if err := raw.limits.Check(&raw.Config); err != nil {
+			return false, err
+		}
+	}
+
+	var hash []byte
+
+	if len(raw.Hash) <= 64 {
+		buf := verifyBuffers.Get().(*[64]byte)
+		defer verifyBuffers.Put(buf)
+		hash = buf[:len(raw.Hash)]
+	} else {
+		hash = make([]byte, len(raw.Hash))
+	}
+
+	err := raw.Config.hashInto(ctx, hash, pwd, raw.Salt, secret, ad)
 	if err != nil {
 		return false, err
 	}
-	return subtle.ConstantTimeCompare(r.Hash, raw.Hash) == 1, nil
+
+	ok := subtle.ConstantTimeCompare(hash, raw.Hash) == 1
+	SecureZeroMemory(hash)
+	return ok, nil
+}
+
+// NeedsRehash returns true if `raw` was computed using different parameters than `target`,
+// which is the case if their Mode, Version, TimeCost, MemoryCost, Parallelism,
+// salt length or hash length differ. See Config.VerifyAndUpgrade().
+func (raw *Raw) NeedsRehash(target Config) bool {
+	c := &raw.Config
+	return c.Mode != target.Mode ||
+		c.Version != target.Version ||
+		c.TimeCost != target.TimeCost ||
+		c.MemoryCost != target.MemoryCost ||
+		c.Parallelism != target.Parallelism ||
+		uint64(len(raw.Salt)) != uint64(target.SaltLength) ||
+		uint64(len(raw.Hash)) != uint64(target.HashLength)
 }
 
 // VerifyEncoded returns true if `pwd` matches the encoded hash `encoded` and otherwise false.
 func VerifyEncoded(pwd []byte, encoded []byte) (bool, error) {
+	return VerifyEncodedWithSecret(pwd, encoded, nil)
+}
+
+// VerifyEncodedWithSecret works like VerifyEncoded(), but for hashes created using HashWithSecret().
+func VerifyEncodedWithSecret(pwd []byte, encoded []byte, secret []byte) (bool, error) {
 	r, err := Decode(encoded)
 	if err != nil {
 		return false, err
 	}
-	return r.Verify(pwd)
+	return r.VerifyWithSecret(pwd, secret)
 }
 
-// SecureZeroMemory is a helper method which sets all
-// bytes in `b` (up to it's capacity) to `0x00`, erasing it's contents.
-func SecureZeroMemory(b []byte) {
-	b = b[:cap(b):cap(b)]
+// VerifyEncodedWithData works like VerifyEncodedWithSecret(),
+// but uses the expected associated data `ad`. See Raw.VerifyWithData().
+func VerifyEncodedWithData(pwd []byte, encoded []byte, secret []byte, ad []byte) (bool, error) {
+	r, err := Decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	return r.VerifyWithData(pwd, secret, ad)
+}
 
-	for i := range b {
-		b[i] = 0
+// VerifyEncodedContext works like VerifyEncoded(), but aborts the computation
+// as soon as `ctx` is done. See Config.HashContext().
+func VerifyEncodedContext(ctx context.Context, pwd []byte, encoded []byte) (bool, error) {
+	r, err := Decode(encoded)
+	if err != nil {
+		return false, err
 	}
+	return r.VerifyContext(ctx, pwd)
+}
+
+// VerifyAndUpgrade works like VerifyEncoded(), but additionally returns a new
+// encoded hash of `pwd` using the Config `c` if `pwd` matches and `encoded`
+// was computed using outdated parameters. See Raw.NeedsRehash().
+// The key identifier and associated data of `encoded` are retained.
+//
+// This allows you to transparently migrate hashes to stronger parameters during login.
+// `upgraded` is nil if `pwd` doesn't match or no upgrade is necessary.
+// Use Limits.VerifyAndUpgrade() to verify untrusted hashes.
+func (c *Config) VerifyAndUpgrade(pwd []byte, encoded []byte) (ok bool, upgraded []byte, err error) {
+	return c.VerifyAndUpgradeWithSecret(pwd, encoded, nil)
+}
+
+// VerifyAndUpgradeWithSecret works like VerifyAndUpgrade(), but for hashes created using HashWithSecret().
+// The upgraded hash is computed using the same `secret`.
+func (c *Config) VerifyAndUpgradeWithSecret(pwd []byte, encoded []byte, secret []byte) (ok bool, upgraded []byte, err error) {
+	return c.verifyAndUpgrade(pwd, encoded, secret, Decode)
+}
+
+// verifyAndUpgrade implements VerifyAndUpgradeWithSecret() using `decode` to decode `encoded`.
+func (c *Config) verifyAndUpgrade(pwd []byte, encoded []byte, secret []byte, decode func([]byte) (*Raw, error)) (ok bool, upgraded []byte, err error) {
+	// pwd and secret are needed for rehashing and are only erased at the end.
+	defer c.clearInputs(pwd, secret)
+
+	raw, err := decode(encoded)
+	if err != nil {
+		return false, nil, err
+	}
+
+	raw.Config.Allocator = c.Allocator
+
+	ok, err = raw.VerifyWithSecret(pwd, secret)
+	if err != nil || !ok || !raw.NeedsRehash(*c) {
+		return ok, nil, err
+	}
+
+	r, err := c.HashWithData(pwd, nil, secret, raw.AssociatedData)
+	if err != nil {
+		return false, nil, err
+	}
+
+	r.KeyID = raw.KeyID
+	return true, r.Encode(), nil
+}
+
+// Wipe erases the Salt and Hash of `raw` using SecureZeroMemory() and sets them to nil.
+// Use it once a Raw struct isn't needed anymore, to reduce the time its contents linger in memory.
+func (raw *Raw) Wipe() {
+	SecureZeroMemory(raw.Salt)
+	SecureZeroMemory(raw.Hash)
+	raw.Salt = nil
+	raw.Hash = nil
 }
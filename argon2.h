@@ -154,7 +154,9 @@ typedef enum Argon2_ErrorCodes {
 
     ARGON2_DECODING_LENGTH_FAIL = -34,
 
-    ARGON2_VERIFY_MISMATCH = -35
+    ARGON2_VERIFY_MISMATCH = -35,
+
+    ARGON2_ABORTED = -36
 } argon2_error_codes;
 
 /* Memory allocator types --- for external allocation */
@@ -215,6 +217,9 @@ typedef struct Argon2_Context {
     deallocate_fptr free_cbk;   /* pointer to memory deallocator */
 
     uint32_t flags; /* array of bool options */
+
+    /* if not NULL, the computation is aborted once this becomes non-zero */
+    const volatile uint32_t *abort_flag;
 } argon2_context;
 
 /* Argon2 primitive type */
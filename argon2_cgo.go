@@ -0,0 +1,261 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build cgo
+
+package argon2
+
+/*
+#include <stdint.h>
+
+#include "argon2.h"
+#include "core.h"
+
+// The Error constants in error.go hard-code these values.
+_Static_assert(ARGON2_OUTPUT_PTR_NULL == -1, "ARGON2_OUTPUT_PTR_NULL");
+_Static_assert(ARGON2_OUTPUT_TOO_SHORT == -2, "ARGON2_OUTPUT_TOO_SHORT");
+_Static_assert(ARGON2_OUTPUT_TOO_LONG == -3, "ARGON2_OUTPUT_TOO_LONG");
+_Static_assert(ARGON2_PWD_TOO_SHORT == -4, "ARGON2_PWD_TOO_SHORT");
+_Static_assert(ARGON2_PWD_TOO_LONG == -5, "ARGON2_PWD_TOO_LONG");
+_Static_assert(ARGON2_SALT_TOO_SHORT == -6, "ARGON2_SALT_TOO_SHORT");
+_Static_assert(ARGON2_SALT_TOO_LONG == -7, "ARGON2_SALT_TOO_LONG");
+_Static_assert(ARGON2_AD_TOO_SHORT == -8, "ARGON2_AD_TOO_SHORT");
+_Static_assert(ARGON2_AD_TOO_LONG == -9, "ARGON2_AD_TOO_LONG");
+_Static_assert(ARGON2_SECRET_TOO_SHORT == -10, "ARGON2_SECRET_TOO_SHORT");
+_Static_assert(ARGON2_SECRET_TOO_LONG == -11, "ARGON2_SECRET_TOO_LONG");
+_Static_assert(ARGON2_TIME_TOO_SMALL == -12, "ARGON2_TIME_TOO_SMALL");
+_Static_assert(ARGON2_TIME_TOO_LARGE == -13, "ARGON2_TIME_TOO_LARGE");
+_Static_assert(ARGON2_MEMORY_TOO_LITTLE == -14, "ARGON2_MEMORY_TOO_LITTLE");
+_Static_assert(ARGON2_MEMORY_TOO_MUCH == -15, "ARGON2_MEMORY_TOO_MUCH");
+_Static_assert(ARGON2_LANES_TOO_FEW == -16, "ARGON2_LANES_TOO_FEW");
+_Static_assert(ARGON2_LANES_TOO_MANY == -17, "ARGON2_LANES_TOO_MANY");
+_Static_assert(ARGON2_PWD_PTR_MISMATCH == -18, "ARGON2_PWD_PTR_MISMATCH");
+_Static_assert(ARGON2_SALT_PTR_MISMATCH == -19, "ARGON2_SALT_PTR_MISMATCH");
+_Static_assert(ARGON2_SECRET_PTR_MISMATCH == -20, "ARGON2_SECRET_PTR_MISMATCH");
+_Static_assert(ARGON2_AD_PTR_MISMATCH == -21, "ARGON2_AD_PTR_MISMATCH");
+_Static_assert(ARGON2_MEMORY_ALLOCATION_ERROR == -22, "ARGON2_MEMORY_ALLOCATION_ERROR");
+_Static_assert(ARGON2_FREE_MEMORY_CBK_NULL == -23, "ARGON2_FREE_MEMORY_CBK_NULL");
+_Static_assert(ARGON2_ALLOCATE_MEMORY_CBK_NULL == -24, "ARGON2_ALLOCATE_MEMORY_CBK_NULL");
+_Static_assert(ARGON2_INCORRECT_PARAMETER == -25, "ARGON2_INCORRECT_PARAMETER");
+_Static_assert(ARGON2_INCORRECT_TYPE == -26, "ARGON2_INCORRECT_TYPE");
+_Static_assert(ARGON2_OUT_PTR_MISMATCH == -27, "ARGON2_OUT_PTR_MISMATCH");
+_Static_assert(ARGON2_THREADS_TOO_FEW == -28, "ARGON2_THREADS_TOO_FEW");
+_Static_assert(ARGON2_THREADS_TOO_MANY == -29, "ARGON2_THREADS_TOO_MANY");
+_Static_assert(ARGON2_MISSING_ARGS == -30, "ARGON2_MISSING_ARGS");
+_Static_assert(ARGON2_ENCODING_FAIL == -31, "ARGON2_ENCODING_FAIL");
+_Static_assert(ARGON2_DECODING_FAIL == -32, "ARGON2_DECODING_FAIL");
+_Static_assert(ARGON2_THREAD_FAIL == -33, "ARGON2_THREAD_FAIL");
+_Static_assert(ARGON2_DECODING_LENGTH_FAIL == -34, "ARGON2_DECODING_LENGTH_FAIL");
+_Static_assert(ARGON2_VERIFY_MISMATCH == -35, "ARGON2_VERIFY_MISMATCH");
+_Static_assert(ARGON2_ABORTED == -36, "ARGON2_ABORTED");
+
+// Contains the numeric parameters of the Config struct below
+typedef struct bindings_argon2_config {
+	uint32_t HashLength;
+	uint32_t SaltLength;
+	uint32_t TimeCost;
+	uint32_t MemoryCost;
+	uint32_t Parallelism;
+	uint32_t Mode;
+	uint32_t Version;
+	uint32_t Threads;
+} bindings_argon2_config;
+
+// Memory passed to bindings_argon2_hash() which is handed out by bindings_allocate().
+// argon2_ctx() allocates on the calling thread, which makes a thread local sufficient.
+static __thread uint8_t* bindings_memory;
+static __thread size_t bindings_memorylen;
+
+static int bindings_allocate(uint8_t** memory, size_t bytes_to_allocate) {
+	if (bytes_to_allocate > bindings_memorylen) {
+		*memory = NULL;
+		return ARGON2_MEMORY_ALLOCATION_ERROR;
+	}
+
+	*memory = bindings_memory;
+	return ARGON2_OK;
+}
+
+static void bindings_free(uint8_t* memory, size_t bytes_to_allocate) {
+	// The memory has already been erased by free_memory() and is freed by the caller.
+}
+
+// A simplified version of argon2_hash()
+//
+// cfg is passed by value, because pointers passed to C make their target escape to the heap.
+// If memory is not NULL it's used instead of allocating memory using malloc().
+int bindings_argon2_hash(const bindings_argon2_config cfg, void* pwd, const uint32_t pwdlen, void* salt, const uint32_t saltlen, void* secret, const uint32_t secretlen, void* ad, const uint32_t adlen, void* hash, const uint32_t hashlen, const uint32_t* abort_flag, void* memory, const size_t memorylen) {
+	argon2_context c = {
+		.out = hash,
+		.outlen = hashlen,
+		.pwd = pwd,
+		.pwdlen = pwdlen,
+		.salt = salt,
+		.saltlen = saltlen,
+		.secret = secret,
+		.secretlen = secretlen,
+		.ad = ad,
+		.adlen = adlen,
+		.t_cost = cfg.TimeCost,
+		.m_cost = cfg.MemoryCost,
+		.lanes = cfg.Parallelism,
+		.threads = cfg.Threads,
+		.version = cfg.Version,
+		.allocate_cbk = NULL,
+		.free_cbk = NULL,
+		.flags = ARGON2_DEFAULT_FLAGS,
+		.abort_flag = abort_flag,
+	};
+
+	if (memory) {
+		bindings_memory = memory;
+		bindings_memorylen = memorylen;
+		c.allocate_cbk = bindings_allocate;
+		c.free_cbk = bindings_free;
+	}
+
+	const int rc = argon2_ctx(&c, cfg.Mode);
+
+	bindings_memory = NULL;
+	bindings_memorylen = 0;
+
+	if (rc != ARGON2_OK) {
+		clear_internal_memory(hash, hashlen);
+	}
+
+	return rc;
+}
+*/
+import "C"
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+// compute writes the argon2 hash for the given inputs into `out`
+// using the C implementation, unless BackendGo was forced using SetBackend.
+//
+// If memory is not nil it's used for the block matrix instead of allocating memory.
+func compute(ctx context.Context, c *Config, out []byte, pwd []byte, salt []byte, secret []byte, ad []byte, memory []byte) error {
+	if atomic.LoadUint32(&useGoBackend) != 0 {
+		return computeGo(ctx, c, out, pwd, salt, secret, ad, memory)
+	}
+
+	// The C code polls abortFlag in between synchronization points.
+	// context.Background() has no Done channel and doesn't need a goroutine.
+	var abortFlag *uint32
+
+	if done := ctx.Done(); done != nil {
+		abortFlag = new(uint32)
+		stop := make(chan struct{})
+		defer close(stop)
+
+		go func() {
+			select {
+			case <-done:
+				atomic.StoreUint32(abortFlag, 1)
+			case <-stop:
+			}
+		}()
+	}
+
+	defer runtime.KeepAlive(out)
+	defer runtime.KeepAlive(pwd)
+	defer runtime.KeepAlive(salt)
+	defer runtime.KeepAlive(secret)
+	defer runtime.KeepAlive(ad)
+	defer runtime.KeepAlive(memory)
+
+	pwdptr := unsafe.Pointer(nil)
+	pwdlen := C.uint32_t(len(pwd))
+	saltptr := unsafe.Pointer(nil)
+	saltlen := C.uint32_t(len(salt))
+	secretptr := unsafe.Pointer(nil)
+	secretlen := C.uint32_t(len(secret))
+	adptr := unsafe.Pointer(nil)
+	adlen := C.uint32_t(len(ad))
+	hashptr := unsafe.Pointer(nil)
+	hashlen := C.uint32_t(len(out))
+
+	if pwdlen > 0 {
+		pwdptr = unsafe.Pointer(&pwd[0])
+	}
+
+	if saltlen > 0 {
+		saltptr = unsafe.Pointer(&salt[0])
+	}
+
+	if secretlen > 0 {
+		secretptr = unsafe.Pointer(&secret[0])
+	}
+
+	if adlen > 0 {
+		adptr = unsafe.Pointer(&ad[0])
+	}
+
+	if hashlen > 0 {
+		hashptr = unsafe.Pointer(&out[0])
+	}
+
+	memptr := unsafe.Pointer(nil)
+	memlen := C.size_t(len(memory))
+
+	if memlen > 0 {
+		memptr = unsafe.Pointer(&memory[0])
+	}
+
+	cfg := C.bindings_argon2_config{
+		HashLength:  C.uint32_t(c.HashLength),
+		SaltLength:  C.uint32_t(c.SaltLength),
+		TimeCost:    C.uint32_t(c.TimeCost),
+		MemoryCost:  C.uint32_t(c.MemoryCost),
+		Parallelism: C.uint32_t(c.Parallelism),
+		Mode:        C.uint32_t(c.Mode),
+		Version:     C.uint32_t(c.Version),
+		Threads:     C.uint32_t(c.threads()),
+	}
+
+	rc := C.bindings_argon2_hash(
+		cfg,
+		pwdptr,
+		pwdlen,
+		saltptr,
+		saltlen,
+		secretptr,
+		secretlen,
+		adptr,
+		adlen,
+		hashptr,
+		hashlen,
+		(*C.uint32_t)(unsafe.Pointer(abortFlag)),
+		memptr,
+		memlen,
+	)
+
+	if rc == C.ARGON2_ABORTED {
+		return ctx.Err()
+	}
+
+	if rc != C.ARGON2_OK {
+		return Error(rc)
+	}
+
+	return nil
+}
+
+// SecureZeroMemory is a helper method which sets all
+// bytes in `b` (up to it's capacity) to `0x00`, erasing it's contents.
+//
+// It calls secure_wipe_memory() of the C implementation, which
+// ensures that the compiler can't optimize the erasure away.
+func SecureZeroMemory(b []byte) {
+	b = b[:cap(b):cap(b)]
+
+	if len(b) > 0 {
+		C.secure_wipe_memory(unsafe.Pointer(&b[0]), C.size_t(len(b)))
+	}
+}
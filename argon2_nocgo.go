@@ -0,0 +1,32 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !cgo
+
+package argon2
+
+import (
+	"context"
+)
+
+// compute writes the argon2 hash for the given inputs into `out`
+// using the pure Go implementation, as cgo is disabled.
+//
+// If memory is not nil it's used for the block matrix instead of allocating memory.
+func compute(ctx context.Context, c *Config, out []byte, pwd []byte, salt []byte, secret []byte, ad []byte, memory []byte) error {
+	return computeGo(ctx, c, out, pwd, salt, secret, ad, memory)
+}
+
+// SecureZeroMemory is a helper method which sets all
+// bytes in `b` (up to it's capacity) to `0x00`, erasing it's contents.
+//
+// The Go compiler doesn't eliminate stores to memory referenced by a
+// slice, which makes this loop equivalent to secure_wipe_memory().
+func SecureZeroMemory(b []byte) {
+	b = b[:cap(b):cap(b)]
+
+	for i := range b {
+		b[i] = 0
+	}
+}
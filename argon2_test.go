@@ -6,9 +6,12 @@ package argon2
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	xcryptoArgon2 "golang.org/x/crypto/argon2"
 )
@@ -101,6 +104,105 @@ func TestHashWithSalt(t *testing.T) {
 	}
 }
 
+func TestHashRand(t *testing.T) {
+	c := config
+	c.SaltLength = uint32(len(salt))
+	c.Rand = bytes.NewReader(salt)
+
+	encoded, err := c.HashEncoded(password)
+	mustBeFalsey(t, "err1", err)
+
+	if !bytes.Equal(encoded, expectedEncoded) {
+		t.Errorf("expected %s, got: %s", expectedEncoded, encoded)
+	}
+
+	// The reader is exhausted now.
+	_, err = c.HashRaw(password)
+	if err != ErrSaltShortRead {
+		t.Errorf("expected ErrSaltShortRead, got: %v", err)
+	}
+
+	c.Rand = bytes.NewReader(salt[:4])
+	_, err = c.HashRaw(password)
+	if err != ErrSaltShortRead {
+		t.Errorf("expected ErrSaltShortRead, got: %v", err)
+	}
+
+	c.SaltLength = 4
+	c.Rand = bytes.NewReader(salt)
+	_, err = c.HashRaw(password)
+	if err != ErrSaltTooShort {
+		t.Errorf("expected ErrSaltTooShort, got: %v", err)
+	}
+	if c.Rand.(*bytes.Reader).Len() != len(salt) {
+		t.Error("no entropy must be consumed for an invalid salt length")
+	}
+}
+
+func TestHashInto(t *testing.T) {
+	hash := make([]byte, config.HashLength)
+	err := config.HashInto(hash, password, salt)
+	mustBeFalsey(t, "err1", err)
+
+	if !bytes.Equal(hash, expectedHash) {
+		t.Errorf("expected %x, got: %x", expectedHash, hash)
+	}
+
+	if err := config.HashInto(hash[:8], password, salt); err != ErrOutPtrMismatch {
+		t.Errorf("expected ErrOutPtrMismatch, got: %v", err)
+	}
+
+	if err := config.HashInto(hash, password, nil); err != ErrSaltTooShort {
+		t.Errorf("expected ErrSaltTooShort, got: %v", err)
+	}
+
+	// The Go backend allocates its block matrix and goroutines using the Go heap.
+	if Implementation().Backend == BackendGo {
+		t.Skip("the Go backend allocates")
+	}
+
+	r, err := Decode(expectedEncoded)
+	mustBeFalsey(t, "err2", err)
+
+	pwd := string(password)
+	buf := make([]byte, 0, 128)
+
+	allocs := testing.AllocsPerRun(3, func() {
+		config.HashIntoString(hash, pwd, salt)
+		r.VerifyString(pwd)
+		buf = r.AppendEncode(buf[:0])
+	})
+	if allocs != 0 {
+		t.Errorf("expected 0 allocations, got: %v", allocs)
+	}
+}
+
+func TestHashString(t *testing.T) {
+	r, err := config.HashString(string(password), salt)
+	mustBeFalsey(t, "err1", err)
+
+	if !bytes.Equal(r.Hash, expectedHash) {
+		t.Errorf("expected %x, got: %x", expectedHash, r.Hash)
+	}
+
+	ok, err := VerifyEncodedString(string(password), string(expectedEncoded))
+	mustBeFalsey(t, "err2", err)
+	if !ok {
+		t.Error("VerifyEncodedString() must succeed")
+	}
+
+	ok, err = r.VerifyString("wrong")
+	mustBeFalsey(t, "err3", err)
+	if ok {
+		t.Error("VerifyString() must fail for a wrong password")
+	}
+
+	encoded := r.AppendEncode([]byte("hash: "))
+	if !bytes.Equal(encoded, append([]byte("hash: "), expectedEncoded...)) {
+		t.Errorf("expected %s to be appended, got: %s", expectedEncoded, encoded)
+	}
+}
+
 func TestVerifyRaw(t *testing.T) {
 	r, err := config.HashRaw(password)
 	mustBeTruthy(t, "r.Config", r.Config)
@@ -123,6 +225,242 @@ func TestVerifyEncoded(t *testing.T) {
 	mustBeFalsey(t, "err2", err)
 }
 
+func TestHashWithSecret(t *testing.T) {
+	secret := []byte("pepper")
+
+	r, err := config.HashWithSecret(password, salt, secret)
+	mustBeTruthy(t, "r", r)
+	mustBeFalsey(t, "err1", err)
+
+	if bytes.Equal(r.Hash, expectedHash) {
+		t.Error("secret must change the hash")
+	}
+
+	ok, err := r.VerifyWithSecret(password, secret)
+	mustBeFalsey(t, "err2", err)
+	if !ok {
+		t.Error("VerifyWithSecret() must succeed using the same secret")
+	}
+
+	ok, err = r.Verify(password)
+	mustBeFalsey(t, "err3", err)
+	if ok {
+		t.Error("Verify() must fail without the secret")
+	}
+
+	ok, err = VerifyEncodedWithSecret(password, r.Encode(), []byte("salt"))
+	mustBeFalsey(t, "err4", err)
+	if ok {
+		t.Error("VerifyEncodedWithSecret() must fail using a different secret")
+	}
+}
+
+// Test vectors from RFC 9106, section 5.
+func TestHashWithDataRFC9106(t *testing.T) {
+	cfg := Config{
+		HashLength:  32,
+		TimeCost:    3,
+		MemoryCost:  32,
+		Parallelism: 4,
+		Version:     Version13,
+	}
+
+	pwd := bytes.Repeat([]byte{0x01}, 32)
+	salt := bytes.Repeat([]byte{0x02}, 16)
+	secret := bytes.Repeat([]byte{0x03}, 8)
+	ad := bytes.Repeat([]byte{0x04}, 12)
+
+	for _, tc := range []struct {
+		mode     Mode
+		expected string
+	}{
+		{ModeArgon2d, "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb"},
+		{ModeArgon2i, "c814d9d1dc7f37aa13f0d77f2494bda1c8de6b016dd388d29952a4c4672b6ce8"},
+		{ModeArgon2id, "0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659"},
+	} {
+		cfg.Mode = tc.mode
+
+		r, err := cfg.HashWithData(pwd, salt, secret, ad)
+		mustBeFalsey(t, "err", err)
+
+		if hex.EncodeToString(r.Hash) != tc.expected {
+			t.Errorf("%s: hashes do not match", tc.mode)
+		}
+	}
+}
+
+func TestVerifyWithData(t *testing.T) {
+	ad := []byte("tenant-1")
+
+	r, err := config.HashWithData(password, salt, nil, ad)
+	mustBeFalsey(t, "err1", err)
+
+	d, err := Decode(r.Encode())
+	mustBeFalsey(t, "err2", err)
+
+	if !bytes.Equal(d.AssociatedData, ad) {
+		t.Error("associated data must survive Encode() and Decode()")
+	}
+
+	ok, err := d.Verify(password)
+	mustBeFalsey(t, "err3", err)
+	if !ok {
+		t.Error("Verify() must succeed using the stored associated data")
+	}
+
+	ok, err = VerifyEncodedWithData(password, r.Encode(), nil, []byte("tenant-2"))
+	mustBeFalsey(t, "err4", err)
+	if ok {
+		t.Error("VerifyEncodedWithData() must fail using different associated data")
+	}
+}
+
+func TestHashContext(t *testing.T) {
+	r, err := config.HashContext(context.Background(), password, salt)
+	mustBeFalsey(t, "err1", err)
+
+	if !bytes.Equal(r.Hash, expectedHash) {
+		t.Error("hashes do not match")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = config.HashContext(ctx, password, salt)
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+
+	for _, parallelism := range []uint32{1, 4} {
+		cfg := config
+		cfg.TimeCost = 100
+		cfg.Parallelism = parallelism
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		start := time.Now()
+		_, err = cfg.HashContext(ctx, password, salt)
+		elapsed := time.Since(start)
+		cancel()
+
+		if err != context.DeadlineExceeded {
+			t.Errorf("p=%d: expected context.DeadlineExceeded, got: %v", parallelism, err)
+		}
+		if elapsed > time.Second {
+			t.Errorf("p=%d: hashing wasn't aborted in time: %v", parallelism, elapsed)
+		}
+	}
+}
+
+func TestVerifyEncodedContext(t *testing.T) {
+	ok, err := VerifyEncodedContext(context.Background(), password, expectedEncoded)
+	mustBeFalsey(t, "err", err)
+	if !ok {
+		t.Error("VerifyEncodedContext() must succeed")
+	}
+}
+
+func TestHashThreads(t *testing.T) {
+	cfg := config
+	cfg.Parallelism = 4
+
+	var expected []byte
+
+	// Threads larger than Parallelism, even beyond the limit of
+	// the C implementation, are capped to Parallelism.
+	for _, threads := range []uint32{0, 1, 2, 4, 8, 1 << 25, 0xFFFFFFFF} {
+		cfg.Threads = threads
+
+		r, err := cfg.Hash(password, salt)
+		mustBeFalsey(t, "err", err)
+
+		if expected == nil {
+			expected = r.Hash
+		} else if !bytes.Equal(r.Hash, expected) {
+			t.Errorf("threads=%d: hashes do not match", threads)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	r, err := config.Hash(password, make([]byte, config.SaltLength))
+	mustBeFalsey(t, "err", err)
+
+	if r.NeedsRehash(config) {
+		t.Error("NeedsRehash() must return false for identical parameters")
+	}
+
+	for i, modify := range []func(c *Config){
+		func(c *Config) { c.Mode = ModeArgon2i },
+		func(c *Config) { c.Version = Version10 },
+		func(c *Config) { c.TimeCost++ },
+		func(c *Config) { c.MemoryCost *= 2 },
+		func(c *Config) { c.Parallelism++ },
+		func(c *Config) { c.SaltLength++ },
+		func(c *Config) { c.HashLength++ },
+	} {
+		target := config
+		modify(&target)
+
+		if !r.NeedsRehash(target) {
+			t.Errorf("#%d: NeedsRehash() must return true", i)
+		}
+	}
+}
+
+func TestVerifyAndUpgrade(t *testing.T) {
+	target := config
+	target.TimeCost = 2
+
+	ok, upgraded, err := target.VerifyAndUpgrade([]byte("wrong"), expectedEncoded)
+	mustBeFalsey(t, "err1", err)
+	mustBeFalsey(t, "upgraded", upgraded)
+	if ok {
+		t.Error("VerifyAndUpgrade() must fail for a wrong password")
+	}
+
+	ok, upgraded, err = target.VerifyAndUpgrade(password, expectedEncoded)
+	mustBeFalsey(t, "err2", err)
+	mustBeTruthy(t, "upgraded", upgraded)
+	if !ok {
+		t.Error("VerifyAndUpgrade() must succeed")
+	}
+
+	r, err := Decode(upgraded)
+	mustBeFalsey(t, "err3", err)
+	if r.NeedsRehash(target) {
+		t.Error("the upgraded hash must use the target parameters")
+	}
+
+	ok, upgraded, err = target.VerifyAndUpgrade(password, r.Encode())
+	mustBeFalsey(t, "err4", err)
+	mustBeFalsey(t, "upgraded", upgraded)
+	if !ok {
+		t.Error("VerifyAndUpgrade() must succeed")
+	}
+
+	// The secret, key identifier and associated data must be retained.
+	r, err = config.HashWithKey(password, salt, []byte("k1"), []byte("secret"), []byte("data"))
+	mustBeFalsey(t, "err5", err)
+
+	ok, upgraded, err = target.VerifyAndUpgradeWithSecret(password, r.Encode(), []byte("secret"))
+	mustBeFalsey(t, "err6", err)
+	if !ok {
+		t.Error("VerifyAndUpgradeWithSecret() must succeed")
+	}
+
+	ok, err = VerifyEncodedWithData(password, upgraded, []byte("secret"), []byte("data"))
+	mustBeFalsey(t, "err7", err)
+	if !ok {
+		t.Error("the upgraded hash must verify with the same secret and associated data")
+	}
+
+	r, err = Decode(upgraded)
+	mustBeFalsey(t, "err8", err)
+	if !bytes.Equal(r.KeyID, []byte("k1")) {
+		t.Errorf("expected key identifier k1, got: %s", r.KeyID)
+	}
+}
+
 func TestSecureZeroMemory(t *testing.T) {
 	pwd := append([]byte(nil), password...)
 
@@ -136,12 +474,132 @@ func TestSecureZeroMemory(t *testing.T) {
 	}
 }
 
+func TestFlagClear(t *testing.T) {
+	isZero := func(b []byte) bool {
+		return bytes.Equal(b, make([]byte, len(b)))
+	}
+
+	c := config
+	pwd := append([]byte(nil), password...)
+	secret := []byte("secret")
+
+	_, err := c.HashWithSecret(pwd, salt, secret)
+	mustBeFalsey(t, "err1", err)
+	if isZero(pwd) || isZero(secret) {
+		t.Error("pwd and secret must be retained without flags")
+	}
+
+	c.Flags = FlagClearPassword | FlagClearSecret
+	_, err = c.HashWithSecret(pwd, salt, secret)
+	mustBeFalsey(t, "err2", err)
+	if !isZero(pwd) || !isZero(secret) {
+		t.Error("pwd and secret must only contain 0x00")
+	}
+
+	// The inputs must be erased on errors as well.
+	pwd = append(pwd[:0], password...)
+	c.SaltLength = 4
+	_, err = c.HashRaw(pwd)
+	if err != ErrSaltTooShort {
+		t.Errorf("expected ErrSaltTooShort, got: %v", err)
+	}
+	if !isZero(pwd) {
+		t.Error("pwd must only contain 0x00")
+	}
+
+	// Strings are immutable and must not be erased.
+	c.SaltLength = config.SaltLength
+	r, err := c.HashString(string(password), salt)
+	mustBeFalsey(t, "err3", err)
+	if !bytes.Equal(r.Hash, expectedHash) {
+		t.Error("HashString() must ignore FlagClearPassword")
+	}
+
+	r.Config.Flags = FlagClearPassword
+	ok, err := r.VerifyString(string(password))
+	mustBeFalsey(t, "err4", err)
+	if !ok {
+		t.Error("VerifyString() must ignore FlagClearPassword")
+	}
+
+	// HashInto() must erase the password if its arguments are rejected as well.
+	for name, hashInto := range map[string]func() error{
+		"dst":  func() error { return c.HashInto(make([]byte, 1), pwd, salt) },
+		"salt": func() error { return c.HashInto(make([]byte, c.HashLength), pwd, nil) },
+	} {
+		pwd = append(pwd[:0], password...)
+		if err := hashInto(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if !isZero(pwd) {
+			t.Errorf("%s: pwd must only contain 0x00", name)
+		}
+	}
+
+	// The returned Raw must not inherit the flags, as verifying
+	// would otherwise erase the caller's password and secret.
+	pwd = append(pwd[:0], password...)
+	secret = []byte("secret")
+	r, err = c.HashWithSecret(append([]byte(nil), password...), salt, append([]byte(nil), secret...))
+	mustBeFalsey(t, "err5", err)
+	if r.Config.Flags != 0 {
+		t.Errorf("expected no flags, got: %d", r.Config.Flags)
+	}
+
+	ok, err = r.VerifyWithSecret(pwd, secret)
+	mustBeFalsey(t, "err6", err)
+	if !ok || isZero(pwd) || isZero(secret) {
+		t.Error("VerifyWithSecret() must succeed and retain pwd and secret")
+	}
+
+	// VerifyAndUpgrade() must only erase the password after rehashing.
+	target := c
+	target.TimeCost = 2
+	pwd = append(pwd[:0], password...)
+	ok, upgraded, err := target.VerifyAndUpgrade(pwd, expectedEncoded)
+	mustBeFalsey(t, "err7", err)
+	if !ok || !isZero(pwd) {
+		t.Error("VerifyAndUpgrade() must succeed and erase pwd")
+	}
+
+	ok, err = VerifyEncoded(password, upgraded)
+	mustBeFalsey(t, "err8", err)
+	if !ok {
+		t.Error("the upgraded hash must be computed using the original password")
+	}
+}
+
+func TestRawWipe(t *testing.T) {
+	r, err := config.Hash(password, append([]byte(nil), salt...))
+	mustBeFalsey(t, "err", err)
+
+	s, h := r.Salt, r.Hash
+	r.Wipe()
+
+	if r.Salt != nil || r.Hash != nil {
+		t.Error("Salt and Hash must be nil")
+	}
+	if !bytes.Equal(s, make([]byte, len(s))) || !bytes.Equal(h, make([]byte, len(h))) {
+		t.Error("Salt and Hash must only contain 0x00")
+	}
+}
+
 func BenchmarkHash(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		config.Hash(password, salt)
 	}
 }
 
+func BenchmarkHashInto(b *testing.B) {
+	hash := make([]byte, config.HashLength)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		config.HashInto(hash, password, salt)
+	}
+}
+
 func BenchmarkHashXCryptoArgon2(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		xcryptoArgon2.IDKey(password, salt, config.TimeCost, config.MemoryCost, uint8(config.Parallelism), config.HashLength)
@@ -161,6 +619,22 @@ func BenchmarkVerify(b *testing.B) {
 	}
 }
 
+func BenchmarkVerifyString(b *testing.B) {
+	r, err := config.Hash(password, salt)
+	if err != nil {
+		b.Error(err)
+	}
+
+	pwd := string(password)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r.VerifyString(pwd)
+	}
+}
+
 func BenchmarkEncode(b *testing.B) {
 	r, err := config.Hash(password, salt)
 	if err != nil {
@@ -175,6 +649,23 @@ func BenchmarkEncode(b *testing.B) {
 	}
 }
 
+func BenchmarkAppendEncode(b *testing.B) {
+	r, err := config.Hash(password, salt)
+	if err != nil {
+		b.Error(err)
+	}
+
+	buf := make([]byte, 0, 128)
+
+	b.SetBytes(int64(len(expectedEncoded)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		buf = r.AppendEncode(buf[:0])
+	}
+}
+
 func BenchmarkDecode(b *testing.B) {
 	b.SetBytes(int64(len(expectedEncoded)))
 	b.ResetTimer()
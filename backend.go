@@ -0,0 +1,117 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"errors"
+)
+
+// Backend identifies an implementation of the Argon2 compression function.
+//
+// See Implementation and SetBackend.
+type Backend int
+
+const (
+	// BackendAuto selects the fastest backend supported by the CPU.
+	// It's only valid as an argument to SetBackend.
+	BackendAuto Backend = 0
+
+	// BackendRef is the portable reference C implementation.
+	BackendRef Backend = 1
+
+	// BackendSSE is the C implementation using SSE2 and SSSE3.
+	BackendSSE Backend = 2
+
+	// BackendAVX2 is the C implementation using AVX2.
+	BackendAVX2 Backend = 3
+
+	// BackendAVX512 is the C implementation using AVX-512F.
+	BackendAVX512 Backend = 4
+
+	// BackendGo is the pure Go implementation.
+	// It's the only backend available if cgo is disabled.
+	BackendGo Backend = 5
+)
+
+// ErrBackendUnsupported is returned by SetBackend if the given backend
+// isn't compiled in or isn't supported by the CPU.
+var ErrBackendUnsupported = errors.New("argon2: backend not supported")
+
+// String maps a Backend constant to a "{auto,ref,sse,avx2,avx512,go}" string
+// or returns "unknown" if `b` does not match one of the constants.
+func (b Backend) String() string {
+	switch b {
+	case BackendAuto:
+		return "auto"
+	case BackendRef:
+		return "ref"
+	case BackendSSE:
+		return "sse"
+	case BackendAVX2:
+		return "avx2"
+	case BackendAVX512:
+		return "avx512"
+	case BackendGo:
+		return "go"
+	default:
+		return "unknown"
+	}
+}
+
+// ImplementationInfo describes how hashes are being computed.
+type ImplementationInfo struct {
+	// Backend is the compression function implementation in use.
+	// It's never BackendAuto.
+	Backend Backend
+
+	// Threading is true if lanes are computed in parallel,
+	// as specified by Config.Threads.
+	Threading bool
+}
+
+// String returns a short description suitable for logging, like "avx2 (threaded)".
+func (i ImplementationInfo) String() string {
+	if i.Threading {
+		return i.Backend.String() + " (threaded)"
+	}
+	return i.Backend.String() + " (single-threaded)"
+}
+
+// Implementation returns the implementation currently used to compute hashes.
+//
+// This allows you to ensure that production binaries
+// are not silently falling back to the reference implementation.
+func Implementation() ImplementationInfo {
+	return ImplementationInfo{
+		Backend:   currentBackend(),
+		Threading: threadingEnabled(),
+	}
+}
+
+// SupportedBackends returns all backends which can be passed to SetBackend
+// on this machine, in the order of the Backend constants.
+func SupportedBackends() []Backend {
+	var backends []Backend
+	for b := BackendRef; b <= BackendGo; b++ {
+		if backendSupported(b) {
+			backends = append(backends, b)
+		}
+	}
+	return backends
+}
+
+// SetBackend forces all subsequent hashes to be computed using the given backend.
+// Passing BackendAuto reverts to the fastest supported one.
+//
+// This is primarily intended for testing and benchmarking and affects the
+// entire process. All backends produce identical hashes, which makes it safe
+// to call SetBackend while hashes are being computed.
+func SetBackend(b Backend) error {
+	if b != BackendAuto && !backendSupported(b) {
+		return ErrBackendUnsupported
+	}
+	setBackend(b)
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build cgo
+
+package argon2
+
+/*
+#include "impl.h"
+*/
+import "C"
+
+import (
+	"sync/atomic"
+)
+
+// useGoBackend is non-zero if BackendGo was forced by SetBackend.
+// compute() then skips the C implementation entirely.
+var useGoBackend uint32
+
+func backendSupported(b Backend) bool {
+	switch b {
+	case BackendGo:
+		return true
+	case BackendRef, BackendSSE, BackendAVX2, BackendAVX512:
+		return C.argon2_impl_supported(C.argon2_impl(b)) != 0
+	default:
+		return false
+	}
+}
+
+func currentBackend() Backend {
+	if atomic.LoadUint32(&useGoBackend) != 0 {
+		return BackendGo
+	}
+	return Backend(C.argon2_impl_get())
+}
+
+func threadingEnabled() bool {
+	if atomic.LoadUint32(&useGoBackend) != 0 {
+		return true
+	}
+	return C.argon2_impl_threading() != 0
+}
+
+func setBackend(b Backend) {
+	if b == BackendGo {
+		atomic.StoreUint32(&useGoBackend, 1)
+		return
+	}
+
+	C.argon2_impl_set(C.argon2_impl(b))
+	atomic.StoreUint32(&useGoBackend, 0)
+}
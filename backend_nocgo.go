@@ -0,0 +1,22 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !cgo
+
+package argon2
+
+func backendSupported(b Backend) bool {
+	return b == BackendGo
+}
+
+func currentBackend() Backend {
+	return BackendGo
+}
+
+func threadingEnabled() bool {
+	return true
+}
+
+func setBackend(b Backend) {
+}
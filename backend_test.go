@@ -0,0 +1,62 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestImplementation(t *testing.T) {
+	impl := Implementation()
+	if impl.Backend == BackendAuto {
+		t.Error("Implementation() must not return BackendAuto")
+	}
+
+	supported := false
+	for _, b := range SupportedBackends() {
+		if b == impl.Backend {
+			supported = true
+		}
+	}
+	if !supported {
+		t.Errorf("%s is missing from SupportedBackends()", impl.Backend)
+	}
+}
+
+// Ensures that every backend supported on this machine produces identical hashes.
+func TestSetBackend(t *testing.T) {
+	defer SetBackend(BackendAuto)
+
+	for _, b := range SupportedBackends() {
+		err := SetBackend(b)
+		mustBeFalsey(t, "err", err)
+		if impl := Implementation(); impl.Backend != b {
+			t.Errorf("expected backend %s, got: %s", b, impl.Backend)
+		}
+
+		for _, tc := range goldenHashes {
+			cfg := Config{HashLength: 80, TimeCost: 2, MemoryCost: 64, Parallelism: 2, Mode: tc.mode, Version: tc.version}
+
+			r, err := cfg.HashWithData(password, salt, []byte("secret"), []byte("data"))
+			mustBeFalsey(t, "err", err)
+
+			if hex.EncodeToString(r.Hash) != tc.expected {
+				t.Errorf("%s: %s v=%s: hashes do not match", b, tc.mode, tc.version)
+			}
+		}
+	}
+
+	err := SetBackend(BackendAuto)
+	mustBeFalsey(t, "err", err)
+	if Implementation().Backend == BackendAuto {
+		t.Error("Implementation() must not return BackendAuto")
+	}
+
+	err = SetBackend(Backend(-1))
+	if err != ErrBackendUnsupported {
+		t.Errorf("expected ErrBackendUnsupported, got: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// This file contains the subset of BLAKE2b (RFC 7693) used by the pure Go implementation.
+
+const (
+	blake2bBlockBytes = 128
+	blake2bOutBytes   = 64
+)
+
+var blake2bIV = [8]uint64{
+	0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1,
+	0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179,
+}
+
+var blake2bSigma = [12][16]byte{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+}
+
+// blake2b is an unkeyed BLAKE2b state with a digest length of 1 to 64 bytes.
+type blake2b struct {
+	h      [8]uint64
+	t      uint64
+	buf    [blake2bBlockBytes]byte
+	buflen int
+	outlen int
+}
+
+func (d *blake2b) init(outlen int) {
+	d.h = blake2bIV
+	d.h[0] ^= 0x01010000 ^ uint64(outlen)
+	d.t = 0
+	d.buflen = 0
+	d.outlen = outlen
+}
+
+// update works like blake2b_update(). The last block is always
+// kept in the buffer, as it needs to be compressed by final().
+func (d *blake2b) update(in []byte) {
+	for len(in) > 0 {
+		if d.buflen == blake2bBlockBytes {
+			d.t += blake2bBlockBytes
+			d.compress(&d.buf, false)
+			d.buflen = 0
+		}
+
+		n := copy(d.buf[d.buflen:], in)
+		d.buflen += n
+		in = in[n:]
+	}
+}
+
+func (d *blake2b) updateUint32(v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	d.update(b[:])
+}
+
+// final writes the digest into out, which must be d.outlen bytes long.
+func (d *blake2b) final(out []byte) {
+	d.t += uint64(d.buflen)
+
+	for i := d.buflen; i < blake2bBlockBytes; i++ {
+		d.buf[i] = 0
+	}
+
+	d.compress(&d.buf, true)
+
+	var buf [blake2bOutBytes]byte
+	for i, v := range d.h {
+		binary.LittleEndian.PutUint64(buf[8*i:], v)
+	}
+
+	copy(out, buf[:d.outlen])
+	*d = blake2b{}
+	wipeBytes(buf[:])
+}
+
+func (d *blake2b) compress(block *[blake2bBlockBytes]byte, last bool) {
+	var m [16]uint64
+	var v [16]uint64
+
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(block[8*i:])
+	}
+
+	copy(v[:8], d.h[:])
+	copy(v[8:], blake2bIV[:])
+	v[12] ^= d.t
+
+	if last {
+		v[14] = ^v[14]
+	}
+
+	for r := range blake2bSigma {
+		s := &blake2bSigma[r]
+		blake2bG(&v, 0, 4, 8, 12, m[s[0]], m[s[1]])
+		blake2bG(&v, 1, 5, 9, 13, m[s[2]], m[s[3]])
+		blake2bG(&v, 2, 6, 10, 14, m[s[4]], m[s[5]])
+		blake2bG(&v, 3, 7, 11, 15, m[s[6]], m[s[7]])
+		blake2bG(&v, 0, 5, 10, 15, m[s[8]], m[s[9]])
+		blake2bG(&v, 1, 6, 11, 12, m[s[10]], m[s[11]])
+		blake2bG(&v, 2, 7, 8, 13, m[s[12]], m[s[13]])
+		blake2bG(&v, 3, 4, 9, 14, m[s[14]], m[s[15]])
+	}
+
+	for i := range d.h {
+		d.h[i] ^= v[i] ^ v[i+8]
+	}
+
+	// The message words may contain the password or secret.
+	m = [16]uint64{}
+	v = [16]uint64{}
+}
+
+func blake2bG(v *[16]uint64, a, b, c, d int, x, y uint64) {
+	v[a] += v[b] + x
+	v[d] = bits.RotateLeft64(v[d]^v[a], -32)
+	v[c] += v[d]
+	v[b] = bits.RotateLeft64(v[b]^v[c], -24)
+	v[a] += v[b] + y
+	v[d] = bits.RotateLeft64(v[d]^v[a], -16)
+	v[c] += v[d]
+	v[b] = bits.RotateLeft64(v[b]^v[c], -63)
+}
+
+// blake2bLong is the variable-length hash function H' of Argon2. It mirrors blake2b_long().
+func blake2bLong(out []byte, in []byte) {
+	var d blake2b
+
+	if len(out) <= blake2bOutBytes {
+		d.init(len(out))
+		d.updateUint32(uint32(len(out)))
+		d.update(in)
+		d.final(out)
+		return
+	}
+
+	var buf [blake2bOutBytes]byte
+
+	d.init(blake2bOutBytes)
+	d.updateUint32(uint32(len(out)))
+	d.update(in)
+	d.final(buf[:])
+
+	copy(out, buf[:32])
+	out = out[32:]
+
+	for len(out) > blake2bOutBytes {
+		d.init(blake2bOutBytes)
+		d.update(buf[:])
+		d.final(buf[:])
+
+		copy(out, buf[:32])
+		out = out[32:]
+	}
+
+	d.init(len(out))
+	d.update(buf[:])
+	d.final(out)
+	wipeBytes(buf[:])
+}
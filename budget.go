@@ -0,0 +1,159 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMemoryBudgetDivisor specifies the fraction of the cgroup memory limit
+	// used as the default memory budget. See SetMemoryBudget.
+	defaultMemoryBudgetDivisor = 2
+
+	// defaultMemoryBudgetTimeout specifies how long hashes wait for the default
+	// memory budget. Hash() etc. use context.Background() and would otherwise
+	// wait forever if the budget is exhausted.
+	defaultMemoryBudgetTimeout = 5 * time.Second
+)
+
+// MemoryBudgetError is returned if a hash couldn't be admitted by the memory budget.
+// See SetMemoryBudget.
+type MemoryBudgetError struct {
+	// Size is the amount of memory in bytes the hash requires.
+	Size uint64
+
+	// Limit is the memory budget in bytes at the time the hash was rejected.
+	Limit uint64
+}
+
+// Error implements the error interface.
+func (e *MemoryBudgetError) Error() string {
+	if e.Size > e.Limit {
+		return fmt.Sprintf("argon2: hash requires %d bytes, exceeding the memory budget of %d bytes", e.Size, e.Limit)
+	}
+	return fmt.Sprintf("argon2: timed out waiting for %d bytes of the memory budget of %d bytes", e.Size, e.Limit)
+}
+
+// SetMemoryBudget limits the total amount of memory in bytes used by all hashes
+// computed at the same time in this process. Hashes wait until enough of the
+// budget is available, instead of risking to be killed by the OOM killer.
+//
+// The timeout specifies how long hashes wait for the budget, before
+// a *MemoryBudgetError is returned. If 0, they wait until their context is done,
+// or until the context's deadline has passed, in which case a *MemoryBudgetError
+// is returned as well. If negative, hashes are rejected without waiting.
+// A hash which requires more memory than the entire budget is always rejected immediately.
+//
+// A limit of 0 disables the budget. By default the budget is half of the
+// cgroup v2 "memory.max" limit with a timeout of 5 seconds, if the process is
+// running in such a cgroup. Inside such containers hashes may thus fail with a
+// *MemoryBudgetError under load, even if SetMemoryBudget has never been called.
+//
+// Memory held by the idle pool of a Hasher isn't counted against the budget.
+func SetMemoryBudget(limit uint64, timeout time.Duration) {
+	globalMemoryBudget.init()
+	globalMemoryBudget.mu.Lock()
+	globalMemoryBudget.limit = limit
+	globalMemoryBudget.timeout = timeout
+	globalMemoryBudget.wakeLocked()
+	globalMemoryBudget.mu.Unlock()
+}
+
+// MemoryBudget returns the current memory budget set by SetMemoryBudget and
+// the amount of memory in bytes currently used by hashes.
+func MemoryBudget() (limit uint64, used uint64) {
+	globalMemoryBudget.init()
+	globalMemoryBudget.mu.Lock()
+	defer globalMemoryBudget.mu.Unlock()
+	return globalMemoryBudget.limit, globalMemoryBudget.used
+}
+
+var globalMemoryBudget memoryBudget
+
+type memoryBudget struct {
+	once sync.Once
+
+	mu      sync.Mutex
+	limit   uint64
+	timeout time.Duration
+	used    uint64
+	changed chan struct{} // closed once memory is released; nil if nobody is waiting
+}
+
+func (b *memoryBudget) init() {
+	b.once.Do(func() {
+		b.limit = cgroupMemoryMax() / defaultMemoryBudgetDivisor
+		b.timeout = defaultMemoryBudgetTimeout
+	})
+}
+
+// reserve waits until `size` bytes of the budget are available and reserves them.
+// Every successful call must be followed by a call to release() with the same size.
+func (b *memoryBudget) reserve(ctx context.Context, size uint64) error {
+	b.init()
+
+	var timeout <-chan time.Time
+
+	for {
+		b.mu.Lock()
+
+		if b.limit == 0 || b.used+size <= b.limit {
+			b.used += size
+			b.mu.Unlock()
+			return nil
+		}
+
+		if size > b.limit || b.timeout < 0 {
+			err := &MemoryBudgetError{Size: size, Limit: b.limit}
+			b.mu.Unlock()
+			return err
+		}
+
+		if timeout == nil && b.timeout > 0 {
+			t := time.NewTimer(b.timeout)
+			defer t.Stop()
+			timeout = t.C
+		}
+
+		if b.changed == nil {
+			b.changed = make(chan struct{})
+		}
+
+		changed := b.changed
+		limit := b.limit
+		b.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timeout:
+			return &MemoryBudgetError{Size: size, Limit: limit}
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return &MemoryBudgetError{Size: size, Limit: limit}
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// release returns memory reserved by reserve() to the budget.
+func (b *memoryBudget) release(size uint64) {
+	b.mu.Lock()
+	b.used -= size
+	b.wakeLocked()
+	b.mu.Unlock()
+}
+
+// wakeLocked wakes up all hashes waiting in reserve(). b.mu must be held.
+func (b *memoryBudget) wakeLocked() {
+	if b.changed != nil {
+		close(b.changed)
+		b.changed = nil
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// cgroupMemoryMax returns the "memory.max" limit of the cgroup v2 the process
+// is running in, or 0 if there's no such limit.
+func cgroupMemoryMax() uint64 {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return 0
+	}
+
+	// The cgroup v2 entry has the format "0::/path".
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte("0::")) {
+			continue
+		}
+		path := line[3:]
+
+		data, err := os.ReadFile(filepath.Join("/sys/fs/cgroup", string(path), "memory.max"))
+		if err != nil {
+			return 0
+		}
+
+		// "max" indicates that there's no limit and fails to parse.
+		limit, err := strconv.ParseUint(string(bytes.TrimSpace(data)), 10, 64)
+		if err != nil {
+			return 0
+		}
+
+		return limit
+	}
+
+	return 0
+}
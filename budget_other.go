@@ -0,0 +1,12 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package argon2
+
+// cgroupMemoryMax returns 0, as cgroups are only supported on Linux.
+func cgroupMemoryMax() uint64 {
+	return 0
+}
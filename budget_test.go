@@ -0,0 +1,100 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	limit, _ := MemoryBudget()
+	defer SetMemoryBudget(limit, globalMemoryBudget.timeout)
+
+	size := config.MemorySize()
+	var berr *MemoryBudgetError
+
+	// Hashes which exceed the entire budget are rejected immediately.
+	SetMemoryBudget(size-1, 0)
+	_, err := config.Hash(password, salt)
+	if !errors.As(err, &berr) || berr.Size != size {
+		t.Errorf("expected a MemoryBudgetError for %d bytes, got: %v", size, err)
+	}
+
+	// Hashes wait for the budget until the timeout passes.
+	SetMemoryBudget(size, 10*time.Millisecond)
+	err = globalMemoryBudget.reserve(context.Background(), size)
+	mustBeFalsey(t, "err", err)
+
+	_, err = config.Hash(password, salt)
+	if !errors.As(err, &berr) {
+		t.Errorf("expected a MemoryBudgetError, got: %v", err)
+	}
+
+	// ...or until their context's deadline has passed.
+	SetMemoryBudget(size, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = config.HashContext(ctx, password, salt)
+	if !errors.As(err, &berr) {
+		t.Errorf("expected a MemoryBudgetError, got: %v", err)
+	}
+
+	// Waiting hashes proceed once the budget is released.
+	done := make(chan error)
+	go func() {
+		_, err := config.Hash(password, salt)
+		done <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	globalMemoryBudget.release(size)
+	mustBeFalsey(t, "err", <-done)
+
+	_, used := MemoryBudget()
+	if used != 0 {
+		t.Errorf("expected the budget to be released, got: %d bytes in use", used)
+	}
+}
+
+// The default budget must not make Hash() etc. wait forever.
+func TestMemoryBudgetDefaultTimeout(t *testing.T) {
+	var b memoryBudget
+	b.init()
+
+	if b.timeout <= 0 {
+		t.Errorf("expected a finite timeout, got: %v", b.timeout)
+	}
+}
+
+func TestMemoryBudgetHasher(t *testing.T) {
+	limit, _ := MemoryBudget()
+	defer SetMemoryBudget(limit, globalMemoryBudget.timeout)
+
+	h, _ := newTestHasher(t, HasherOptions{})
+	defer h.Close()
+
+	SetMemoryBudget(h.MemorySize()-1, 0)
+	_, err := h.Hash(password, salt)
+	var berr *MemoryBudgetError
+	if !errors.As(err, &berr) {
+		t.Errorf("expected a MemoryBudgetError, got: %v", err)
+	}
+
+	SetMemoryBudget(h.MemorySize(), 0)
+	_, err = h.Hash(password, salt)
+	mustBeFalsey(t, "err", err)
+
+	_, used := MemoryBudget()
+	if used != 0 {
+		t.Errorf("expected the budget to be released, got: %d bytes in use", used)
+	}
+	if n := len(h.pool.slots); n != 0 {
+		t.Errorf("expected all slots to be released, got: %d", n)
+	}
+}
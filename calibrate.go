@@ -0,0 +1,355 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// ErrCalibrationTarget is returned by Calibrate() if even the weakest
+// permissible Config takes longer than the target duration.
+var ErrCalibrationTarget = errors.New("argon2: target duration is too short for the given limits")
+
+// CalibrateOptions contains the options for Calibrate().
+type CalibrateOptions struct {
+	// Duration specifies the maximum duration of a single hash. Must be > 0.
+	Duration time.Duration
+
+	// MaxMemory specifies the maximum amount of memory in bytes a single hash may use.
+	// Must be at least 8 KiB per lane.
+	MaxMemory uint64
+
+	// MaxParallelism specifies the maximum amount of lanes to use.
+	// Calibrate() tries 1 lane, every power of 2 up to MaxParallelism and
+	// MaxParallelism itself and returns the strongest of the resulting Configs.
+	//
+	// If 0, GOMAXPROCS is used.
+	MaxParallelism uint32
+
+	// Base specifies the Mode, Version, HashLength and SaltLength of the resulting Config.
+	//
+	// If its HashLength is 0, DefaultConfig() is used.
+	Base Config
+
+	// Samples specifies the amount of hashes computed for every candidate Config.
+	// The median of their durations is compared against Duration.
+	//
+	// If 0, 5 samples are used.
+	Samples int
+}
+
+// Calibration contains the strongest parameters found by Calibrate(),
+// including the measurements and the environment they were made in.
+type Calibration struct {
+	Mode        Mode    `json:"mode"`
+	Version     Version `json:"version"`
+	HashLength  uint32  `json:"hashLength"`
+	SaltLength  uint32  `json:"saltLength"`
+	TimeCost    uint32  `json:"timeCost"`
+	MemoryCost  uint32  `json:"memoryCost"`
+	Parallelism uint32  `json:"parallelism"`
+
+	// Samples, Min, Median and Max describe the duration of hashes using these parameters.
+	Samples int           `json:"samples"`
+	Min     time.Duration `json:"min"`
+	Median  time.Duration `json:"median"`
+	Max     time.Duration `json:"max"`
+
+	// Key identifies the options and environment of the calibration. See CalibrateCached().
+	Key     CalibrationKey `json:"key"`
+	Created time.Time      `json:"created"`
+}
+
+// CalibrationKey identifies the options and environment of a calibration.
+// A cached Calibration is only reused if its key matches.
+type CalibrationKey struct {
+	Duration       time.Duration `json:"duration"`
+	MaxMemory      uint64        `json:"maxMemory"`
+	MaxParallelism uint32        `json:"maxParallelism"`
+	Mode           Mode          `json:"mode"`
+	Version        Version       `json:"version"`
+	HashLength     uint32        `json:"hashLength"`
+	SaltLength     uint32        `json:"saltLength"`
+	Samples        int           `json:"samples"`
+	GOARCH         string        `json:"goarch"`
+	NumCPU         int           `json:"numCPU"`
+	Backend        string        `json:"backend"`
+}
+
+// Config returns a Config using the calibrated parameters.
+func (c *Calibration) Config() Config {
+	return Config{
+		HashLength:  c.HashLength,
+		SaltLength:  c.SaltLength,
+		TimeCost:    c.TimeCost,
+		MemoryCost:  c.MemoryCost,
+		Parallelism: c.Parallelism,
+		Mode:        c.Mode,
+		Version:     c.Version,
+	}
+}
+
+// Calibrate benchmarks Config.Hash() on the current machine and returns the
+// strongest Config whose hashes take at most opts.Duration and opts.MaxMemory.
+//
+// Following RFC 9106 the memory cost is maximized first, after which the
+// time cost is increased for as long as the target duration permits.
+// This is repeated for every candidate Parallelism (see CalibrateOptions)
+// and the Config with the highest product of MemoryCost and TimeCost wins.
+// Calibration computes many hashes and may take a few seconds.
+func Calibrate(opts CalibrateOptions) (*Calibration, error) {
+	opts = opts.withDefaults()
+
+	if opts.Duration <= 0 {
+		return nil, ErrIncorrectParameter
+	}
+
+	var best *Calibration
+	var firstErr error
+
+	for p := uint32(1); ; p *= 2 {
+		if p >= opts.MaxParallelism || p > maxLanes {
+			p = opts.MaxParallelism
+		}
+
+		c, err := calibrateLanes(opts, p)
+		switch err {
+		case nil:
+			if best == nil || uint64(c.MemoryCost)*uint64(c.TimeCost) > uint64(best.MemoryCost)*uint64(best.TimeCost) {
+				best = c
+			}
+		case ErrMemoryTooLittle, ErrCalibrationTarget:
+			// More lanes need more memory and may not fit either, but can still be faster.
+			if firstErr == nil {
+				firstErr = err
+			}
+		default:
+			return nil, err
+		}
+
+		if p == opts.MaxParallelism {
+			break
+		}
+	}
+
+	if best == nil {
+		return nil, firstErr
+	}
+
+	best.Key = opts.key()
+	best.Created = time.Now().UTC().Truncate(time.Second)
+	return best, nil
+}
+
+// calibrateLanes returns the strongest Config for Calibrate() using exactly `p` lanes.
+func calibrateLanes(opts CalibrateOptions, p uint32) (*Calibration, error) {
+	lowest := minMemory * p
+	highest := opts.MaxMemory / 1024
+
+	if highest > maxMemory {
+		highest = maxMemory
+	}
+
+	if highest < uint64(lowest) {
+		return nil, ErrMemoryTooLittle
+	}
+
+	cfg := opts.Base
+	cfg.Parallelism = p
+	cfg.TimeCost = 1
+	cfg.MemoryCost = uint32(highest)
+
+	// Reduce the memory cost until a single pass fits into the target duration.
+	// The duration scales roughly linearly with the memory cost.
+	best, err := measure(cfg, opts.Samples)
+	if err != nil {
+		return nil, err
+	}
+
+	for best.Median > opts.Duration {
+		if cfg.MemoryCost == lowest {
+			return nil, ErrCalibrationTarget
+		}
+
+		m := uint64(float64(cfg.MemoryCost) * 0.9 * float64(opts.Duration) / float64(best.Median))
+		if m >= uint64(cfg.MemoryCost) {
+			m = uint64(cfg.MemoryCost) - 1
+		}
+		if m < uint64(lowest) {
+			m = uint64(lowest)
+		}
+		cfg.MemoryCost = uint32(m)
+
+		best, err = measure(cfg, opts.Samples)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Increase the time cost for as long as the target duration permits.
+	// The duration scales roughly linearly with the time cost as well.
+	perPass := best.Median
+
+	for {
+		next := cfg
+		next.TimeCost++
+
+		if perPass > 0 {
+			if t := uint64(opts.Duration / perPass); t > uint64(next.TimeCost) {
+				next.TimeCost = uint32(t)
+			}
+		}
+
+		for next.TimeCost > cfg.TimeCost {
+			c, err := measure(next, opts.Samples)
+			if err != nil {
+				return nil, err
+			}
+
+			if c.Median <= opts.Duration {
+				best = c
+				break
+			}
+
+			t := uint64(float64(next.TimeCost) * float64(opts.Duration) / float64(c.Median))
+			if t >= uint64(next.TimeCost) {
+				t = uint64(next.TimeCost) - 1
+			}
+			if t < uint64(cfg.TimeCost) {
+				t = uint64(cfg.TimeCost)
+			}
+			next.TimeCost = uint32(t)
+		}
+
+		if next.TimeCost == cfg.TimeCost {
+			break
+		}
+
+		perPass = best.Median / time.Duration(next.TimeCost)
+		cfg = next
+	}
+
+	return best, nil
+}
+
+// CalibrateCached works like Calibrate(), but caches the result as JSON in the file at `path`.
+//
+// The cached result is reused as long as opts, the CPU architecture, the amount
+// of CPUs and the backend (see Implementation()) haven't changed. This allows
+// services to skip the calibration when restarting on the same machine.
+func CalibrateCached(path string, opts CalibrateOptions) (*Calibration, error) {
+	opts = opts.withDefaults()
+
+	if data, err := os.ReadFile(path); err == nil {
+		c := &Calibration{}
+		if json.Unmarshal(data, c) == nil && c.Key == opts.key() {
+			return c, nil
+		}
+	}
+
+	c, err := Calibrate(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	// Write to a temporary file first, so that concurrently starting
+	// services never observe a partially written file.
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (opts CalibrateOptions) withDefaults() CalibrateOptions {
+	if opts.MaxParallelism == 0 {
+		opts.MaxParallelism = uint32(runtime.GOMAXPROCS(0))
+	}
+
+	if opts.Base.HashLength == 0 {
+		opts.Base = DefaultConfig()
+	}
+
+	if opts.Samples <= 0 {
+		opts.Samples = 5
+	}
+
+	return opts
+}
+
+func (opts CalibrateOptions) key() CalibrationKey {
+	return CalibrationKey{
+		Duration:       opts.Duration,
+		MaxMemory:      opts.MaxMemory,
+		MaxParallelism: opts.MaxParallelism,
+		Mode:           opts.Base.Mode,
+		Version:        opts.Base.Version,
+		HashLength:     opts.Base.HashLength,
+		SaltLength:     opts.Base.SaltLength,
+		Samples:        opts.Samples,
+		GOARCH:         runtime.GOARCH,
+		NumCPU:         runtime.NumCPU(),
+		Backend:        Implementation().Backend.String(),
+	}
+}
+
+// measure computes `samples` hashes using `cfg` and returns their durations.
+func measure(cfg Config, samples int) (*Calibration, error) {
+	pwd := []byte("password")
+	salt := make([]byte, cfg.SaltLength)
+	durations := make([]time.Duration, samples)
+
+	for i := range durations {
+		start := time.Now()
+		_, err := cfg.Hash(pwd, salt)
+		durations[i] = time.Since(start)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+
+	return &Calibration{
+		Mode:        cfg.Mode,
+		Version:     cfg.Version,
+		HashLength:  cfg.HashLength,
+		SaltLength:  cfg.SaltLength,
+		TimeCost:    cfg.TimeCost,
+		MemoryCost:  cfg.MemoryCost,
+		Parallelism: cfg.Parallelism,
+		Samples:     samples,
+		Min:         durations[0],
+		Median:      durations[samples/2],
+		Max:         durations[samples-1],
+	}, nil
+}
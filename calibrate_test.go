@@ -0,0 +1,84 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var calibrateOptions = CalibrateOptions{
+	Duration:       50 * time.Millisecond,
+	MaxMemory:      4 << 20,
+	MaxParallelism: 2,
+	Samples:        1,
+}
+
+func TestCalibrate(t *testing.T) {
+	c, err := Calibrate(calibrateOptions)
+	mustBeFalsey(t, "err", err)
+
+	cfg := c.Config()
+	if cfg.MemorySize() > calibrateOptions.MaxMemory {
+		t.Errorf("expected at most %d bytes of memory, got: %d", calibrateOptions.MaxMemory, cfg.MemorySize())
+	}
+	if cfg.Parallelism < 1 || cfg.Parallelism > 2 || cfg.TimeCost < 1 || cfg.Mode != ModeArgon2id {
+		t.Errorf("unexpected parameters: %+v", cfg)
+	}
+	if c.Min > c.Median || c.Median > c.Max {
+		t.Errorf("inconsistent statistics: %+v", c)
+	}
+
+	_, err = cfg.Hash(password, salt)
+	mustBeFalsey(t, "err", err)
+
+	// 3 lanes don't fit into 16 KiB, but 1 and 2 lanes do.
+	c, err = Calibrate(CalibrateOptions{Duration: 5 * time.Millisecond, MaxMemory: 16 << 10, MaxParallelism: 3, Samples: 1})
+	mustBeFalsey(t, "err", err)
+
+	if c != nil && (c.Parallelism > 2 || c.MemoryCost != 16) {
+		t.Errorf("unexpected parameters: %+v", c.Config())
+	}
+
+	_, err = Calibrate(CalibrateOptions{Duration: time.Second, MaxMemory: 1024})
+	if err != ErrMemoryTooLittle {
+		t.Errorf("expected ErrMemoryTooLittle, got: %v", err)
+	}
+
+	_, err = Calibrate(CalibrateOptions{Duration: time.Nanosecond, MaxMemory: 1 << 20, MaxParallelism: 1, Samples: 1})
+	if err != ErrCalibrationTarget {
+		t.Errorf("expected ErrCalibrationTarget, got: %v", err)
+	}
+}
+
+func TestCalibrateCached(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calibration.json")
+
+	c1, err := CalibrateCached(path, calibrateOptions)
+	mustBeFalsey(t, "err1", err)
+
+	_, err = os.Stat(path)
+	mustBeFalsey(t, "err2", err)
+
+	c2, err := CalibrateCached(path, calibrateOptions)
+	mustBeFalsey(t, "err3", err)
+
+	if *c1 != *c2 {
+		t.Errorf("expected the cached calibration %+v, got: %+v", c1, c2)
+	}
+
+	// Different options must invalidate the cache.
+	opts := calibrateOptions
+	opts.MaxParallelism = 1
+
+	c3, err := CalibrateCached(path, opts)
+	mustBeFalsey(t, "err4", err)
+
+	if c3.Parallelism != 1 {
+		t.Errorf("expected a new calibration, got: %+v", c3)
+	}
+}
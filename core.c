@@ -1,3 +1,5 @@
+//go:build cgo
+
 /*
  * Argon2 reference source code package - reference C implementations
  *
@@ -245,12 +247,29 @@ uint32_t index_alpha(const argon2_instance_t *instance,
     return absolute_position;
 }
 
+/* Returns non-zero if the abort_flag of the context has been set */
+static int is_aborted(const argon2_instance_t *instance) {
+    const argon2_context *context = instance->context_ptr;
+
+    if (context == NULL || context->abort_flag == NULL) {
+        return 0;
+    }
+#if defined(__GNUC__) || defined(__clang__)
+    return __atomic_load_n(context->abort_flag, __ATOMIC_RELAXED) != 0;
+#else
+    return *context->abort_flag != 0;
+#endif
+}
+
 /* Single-threaded version for p=1 case */
 static int fill_memory_blocks_st(argon2_instance_t *instance) {
     uint32_t r, s, l;
 
     for (r = 0; r < instance->passes; ++r) {
         for (s = 0; s < ARGON2_SYNC_POINTS; ++s) {
+            if (is_aborted(instance)) {
+                return ARGON2_ABORTED;
+            }
             for (l = 0; l < instance->lanes; ++l) {
                 argon2_position_t position = {r, l, (uint8_t)s, 0};
                 fill_segment(instance, position);
@@ -340,6 +359,12 @@ static int fill_memory_blocks_mt(argon2_instance_t *instance) {
                     goto fail;
                 }
             }
+
+            /* 4. Stop at the synchronization point if aborted */
+            if (is_aborted(instance)) {
+                rc = ARGON2_ABORTED;
+                goto fail;
+            }
         }
 
 #ifdef GENKAT
@@ -0,0 +1,301 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DecodeError is returned by DecodeStrict() and DecodeLenient() and
+// describes where and why an encoded hash is malformed.
+type DecodeError struct {
+	// Offset is the position of the offending byte in the encoded hash.
+	Offset int
+
+	// Reason describes the error, e.g. `expected ",t="`.
+	Reason string
+
+	// Err is ErrIncorrectType if the hash doesn't use a known Argon2 identifier and ErrDecodingFail otherwise.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("argon2: malformed hash at offset %d: %s", e.Offset, e.Reason)
+}
+
+// Unwrap returns e.Err, which allows using errors.Is(err, ErrDecodingFail) etc.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeStrict works like Decode(), but enforces the PHC string format exactly:
+//
+//	$argon2<d|i|id>$v=<16|19>$m=<m>,t=<t>,p=<p>[,keyid=<b64>][,data=<b64>]$<salt b64>$<hash b64>
+//
+// Parameters must appear in this order, integers must not have leading zeros,
+// Base64 must be unpadded and canonical and no trailing data is permitted.
+// The parameters must furthermore pass Config.Validate().
+//
+// Errors are returned as a *DecodeError.
+func DecodeStrict(encoded []byte) (*Raw, error) {
+	return decodePHC(encoded, true)
+}
+
+// DecodeLenient works like DecodeStrict(), but accepts the following deviations
+// found in hashes produced by older or non-conforming implementations:
+//
+//   - A missing "v=" segment, which implies Version10, as produced by
+//     versions of the reference implementation prior to Version13.
+//   - Integers with leading zeros.
+//   - Padded Base64, using 1 or 2 "=" as required by its length.
+//   - Parameters which fail Config.Validate(), as long as they're non-zero.
+//
+// Errors are returned as a *DecodeError.
+func DecodeLenient(encoded []byte) (*Raw, error) {
+	return decodePHC(encoded, false)
+}
+
+// A helper for DecodeStrict() and DecodeLenient().
+// Unlike parser it tracks the offset of every field for error reporting.
+type phcDecoder struct {
+	buf    []byte
+	off    int
+	strict bool
+}
+
+func (d *phcDecoder) errorf(off int, format string, args ...interface{}) error {
+	return &DecodeError{Offset: off, Reason: fmt.Sprintf(format, args...), Err: ErrDecodingFail}
+}
+
+// Consumes `s` or returns an error if the next bytes don't match.
+func (d *phcDecoder) expect(s []byte) error {
+	if !bytes.HasPrefix(d.buf[d.off:], s) {
+		return d.errorf(d.off, "expected %q", s)
+	}
+	d.off += len(s)
+	return nil
+}
+
+// Consumes `s` and returns true if the next bytes match.
+func (d *phcDecoder) accept(s []byte) bool {
+	if bytes.HasPrefix(d.buf[d.off:], s) {
+		d.off += len(s)
+		return true
+	}
+	return false
+}
+
+// Parses a non-zero decimal integer.
+func (d *phcDecoder) uint32(name string) (uint32, error) {
+	start := d.off
+	r := uint64(0)
+
+	for d.off < len(d.buf) && '0' <= d.buf[d.off] && d.buf[d.off] <= '9' {
+		r = r*10 + uint64(d.buf[d.off]-'0')
+		if r > 0xFFFFFFFF {
+			return 0, d.errorf(start, "%s overflows uint32", name)
+		}
+		d.off++
+	}
+
+	switch {
+	case d.off == start:
+		return 0, d.errorf(start, "expected a decimal integer for %s", name)
+	case r == 0:
+		return 0, d.errorf(start, "%s must not be 0", name)
+	case d.strict && d.buf[start] == '0':
+		return 0, d.errorf(start, "%s has leading zeros", name)
+	}
+
+	return uint32(r), nil
+}
+
+// Parses a non-empty Base64 value up to the next ',' or '$' or the end of the input.
+func (d *phcDecoder) base64(name string) ([]byte, error) {
+	start := d.off
+	end := start
+
+	for end < len(d.buf) && d.buf[end] != ',' && d.buf[end] != '$' {
+		end++
+	}
+
+	src := d.buf[start:end]
+	if !d.strict {
+		src = bytes.TrimRight(src, "=")
+
+		// Padded Base64 consists of groups of 4 characters, the last of
+		// which contains 2 or 3 characters followed by 2 or 1 "=" respectively.
+		if pad := end - start - len(src); pad != 0 && (pad > 2 || (len(src)+pad)%4 != 0) {
+			return nil, d.errorf(start+len(src), "invalid padding in %s", name)
+		}
+	}
+
+	if len(src) == 0 {
+		return nil, d.errorf(start, "%s is empty", name)
+	}
+
+	// The decoders in encoding/base64 silently skip newlines, which is why
+	// the alphabet is checked here. This also yields a more precise offset.
+	for i, c := range src {
+		if !('A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '+' || c == '/') {
+			return nil, d.errorf(start+i, "invalid Base64 character %q in %s", c, name)
+		}
+	}
+
+	enc := enc64
+	if d.strict {
+		enc = enc64.Strict()
+	}
+
+	dst := make([]byte, enc.DecodedLen(len(src)))
+	n, err := enc.Decode(dst, src)
+	if err != nil {
+		return nil, d.errorf(start, "%s is not valid Base64", name)
+	}
+
+	d.off = end
+	return dst[:n], nil
+}
+
+// phcOffsets contains the offsets of the encoded fields of a Config.
+type phcOffsets struct {
+	mode, v, m, t, p, salt, hash int
+}
+
+// of returns the offset of the Config field `field`, as named by FieldError,
+// or false if the field isn't encoded.
+func (o *phcOffsets) of(field string) (int, bool) {
+	switch field {
+	case "Mode":
+		return o.mode, true
+	case "Version":
+		return o.v, true
+	case "MemoryCost":
+		return o.m, true
+	case "TimeCost":
+		return o.t, true
+	case "Parallelism":
+		return o.p, true
+	case "SaltLength":
+		return o.salt, true
+	case "HashLength":
+		return o.hash, true
+	default:
+		return 0, false
+	}
+}
+
+func decodePHC(encoded []byte, strict bool) (*Raw, error) {
+	d := &phcDecoder{buf: encoded, strict: strict}
+	r := &Raw{}
+	c := &r.Config
+
+	if !d.accept(decChunk1) {
+		return nil, &DecodeError{Offset: 0, Reason: fmt.Sprintf("expected %q", decChunk1), Err: ErrIncorrectType}
+	}
+
+	// Offsets of the fields checked by Config.Validate() below.
+	var offsets phcOffsets
+
+	offsets.mode = d.off
+	switch {
+	case d.accept([]byte("id$")):
+		c.Mode = ModeArgon2id
+	case d.accept([]byte("i$")):
+		c.Mode = ModeArgon2i
+	case d.accept([]byte("d$")):
+		c.Mode = ModeArgon2d
+	default:
+		return nil, &DecodeError{Offset: d.off, Reason: "unknown type", Err: ErrIncorrectType}
+	}
+
+	offsets.v = d.off
+	if d.accept(decChunk2) {
+		v, err := d.uint32("version")
+		if err != nil {
+			return nil, err
+		}
+		if v > 255 {
+			return nil, d.errorf(offsets.v+len(decChunk2), "unknown version %d", v)
+		}
+		c.Version = Version(v)
+		if err := d.expect(decChunk3); err != nil {
+			return nil, err
+		}
+	} else if !strict && d.accept(decChunk3[1:]) {
+		c.Version = Version10
+	} else {
+		return nil, d.errorf(d.off, "expected %q", decChunk2)
+	}
+
+	var err error
+
+	offsets.m = d.off
+	if c.MemoryCost, err = d.uint32("m"); err != nil {
+		return nil, err
+	}
+	if err = d.expect(decChunk4); err != nil {
+		return nil, err
+	}
+	offsets.t = d.off
+	if c.TimeCost, err = d.uint32("t"); err != nil {
+		return nil, err
+	}
+	if err = d.expect(decChunk5); err != nil {
+		return nil, err
+	}
+	offsets.p = d.off
+	if c.Parallelism, err = d.uint32("p"); err != nil {
+		return nil, err
+	}
+
+	if d.accept(decChunk7) {
+		if r.KeyID, err = d.base64("keyid"); err != nil {
+			return nil, err
+		}
+	}
+	if d.accept(decChunk6) {
+		if r.AssociatedData, err = d.base64("data"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = d.expect([]byte("$")); err != nil {
+		return nil, err
+	}
+	offsets.salt = d.off
+	if r.Salt, err = d.base64("salt"); err != nil {
+		return nil, err
+	}
+	if err = d.expect([]byte("$")); err != nil {
+		return nil, err
+	}
+	offsets.hash = d.off
+	if r.Hash, err = d.base64("hash"); err != nil {
+		return nil, err
+	}
+	if d.off != len(d.buf) {
+		return nil, d.errorf(d.off, "unexpected trailing data")
+	}
+
+	c.SaltLength = uint32(len(r.Salt))
+	c.HashLength = uint32(len(r.Hash))
+
+	if strict {
+		if errs, ok := c.Validate().(ValidationError); ok {
+			fe := errs[0]
+			off, ok := offsets.of(fe.Field)
+			if !ok {
+				off = d.off
+			}
+			return nil, d.errorf(off, "%s", strings.TrimPrefix(fe.Error(), "argon2: "))
+		}
+	}
+
+	return r, nil
+}
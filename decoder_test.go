@@ -0,0 +1,143 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDecodeStrict(t *testing.T) {
+	r, err := DecodeStrict(expectedEncoded)
+	mustBeFalsey(t, "err", err)
+
+	if !bytes.Equal(r.Encode(), expectedEncoded) || !bytes.Equal(r.Hash, expectedHash) {
+		t.Errorf("expected %s, got: %s", expectedEncoded, r.Encode())
+	}
+
+	for _, tc := range []struct {
+		encoded string
+		offset  int
+		err     error
+	}{
+		{"$argon3id$v=19$m=32768,t=1,p=1$c2FsdHNhbHQ$aGFzaA", 0, ErrIncorrectType},
+		{"$argon2ix$v=19$m=32768,t=1,p=1$c2FsdHNhbHQ$aGFzaA", 7, ErrIncorrectType},
+		{"$argon2id$m=32768,t=1,p=1$c2FsdHNhbHQ$aGFzaA", 10, ErrDecodingFail},
+		{"$argon2id$v=19$m=032768,t=1,p=1$c2FsdHNhbHQ$aGFzaA", 17, ErrDecodingFail},
+		{"$argon2id$v=19$m=32768,p=1,t=1$c2FsdHNhbHQ$aGFzaA", 22, ErrDecodingFail},
+		{"$argon2id$v=19$m=32768,t=1,p=1,x=1$c2FsdHNhbHQ$aGFzaA", 30, ErrDecodingFail},
+		{"$argon2id$v=19$m=32768,t=1,p=1,data=ZGF0YQ,keyid=azE$c2FsdHNhbHQ$aGFzaA", 42, ErrDecodingFail},
+		{"$argon2id$v=19$m=32768,t=1,p=1,keyid=$c2FsdHNhbHQ$aGFzaA", 37, ErrDecodingFail},
+		{"$argon2id$v=19$m=32768,t=1,p=1$c2FsdHNhbHQ=$aGFzaA", 42, ErrDecodingFail},
+		{"$argon2id$v=19$m=32768,t=1,p=1$c2FsdHNh\nbHQ$aGFzaA", 39, ErrDecodingFail},
+		{"$argon2id$v=19$m=32768,t=1,p=1$c2FsdHNhbHR$aGFzaA", 31, ErrDecodingFail},
+		{"$argon2id$v=19$m=32768,t=1,p=1$c2FsdHNhbHQ$aGFzaA$", 49, ErrDecodingFail},
+		{"$argon2id$v=19$m=4294967296,t=1,p=1$c2FsdHNhbHQ$aGFzaA", 17, ErrDecodingFail},
+		{"$argon2id$v=18$m=32768,t=1,p=1$c2FsdHNhbHQ$aGFzaA", 10, ErrDecodingFail},
+		{"$argon2id$v=19$m=4,t=1,p=1$c2FsdHNhbHQ$aGFzaA", 17, ErrDecodingFail},
+		{"$argon2id$v=19$m=32768,t=1,p=1$c2FsdA$aGFzaA", 31, ErrDecodingFail},
+	} {
+		_, err := DecodeStrict([]byte(tc.encoded))
+
+		var de *DecodeError
+		if !errors.As(err, &de) {
+			t.Errorf("%q: expected a DecodeError, got: %v", tc.encoded, err)
+			continue
+		}
+		if de.Offset != tc.offset || !errors.Is(err, tc.err) {
+			t.Errorf("%q: expected offset %d and %v, got: %v", tc.encoded, tc.offset, tc.err, err)
+		}
+	}
+}
+
+func TestDecodeLenient(t *testing.T) {
+	for _, encoded := range []string{
+		"$argon2i$m=32768,t=1,p=1$c2FsdHNhbHQ$aGFzaA",
+		"$argon2i$v=16$m=032768,t=01,p=1$c2FsdHNhbHQ=$aGFzaA==",
+	} {
+		r, err := DecodeLenient([]byte(encoded))
+		mustBeFalsey(t, encoded, err)
+		if err != nil {
+			continue
+		}
+
+		expected := []byte("$argon2i$v=16$m=32768,t=1,p=1$c2FsdHNhbHQ$aGFzaA")
+		if !bytes.Equal(r.Encode(), expected) {
+			t.Errorf("expected %s, got: %s", expected, r.Encode())
+		}
+	}
+
+	for _, encoded := range []string{
+		"$argon2i$v=16$m=0,t=1,p=1$c2FsdHNhbHQ$aGFzaA",
+		"$argon2i$v=16$m=32768,t=1,p=1$c2FsdHNhbHQ$aGFzaA junk",
+		"$argon2i$v=16$m=32768,t=1,p=1$$aGFzaA",
+		"$argon2i$v=16$m=32768,t=1,p=1$c2FsdHNhbHQ===$aGFzaA",
+		"$argon2i$v=16$m=32768,t=1,p=1$c2FsdHNhbHQ==$aGFzaA",
+		"$argon2i$v=16$m=32768,t=1,p=1$c2FsdHNhbHQ$aGFzaA=",
+		"$argon2i$v=16$m=32768,t=1,p=1$c2FsdHNhbHQ$aGFzaA=========",
+	} {
+		var de *DecodeError
+		if _, err := DecodeLenient([]byte(encoded)); !errors.As(err, &de) {
+			t.Errorf("%q: expected a DecodeError, got: %v", encoded, err)
+		}
+	}
+}
+
+// Ensures that every field reported by Config.Validate() is mapped to an offset.
+func TestDecodeOffsets(t *testing.T) {
+	c := Config{Mode: 3}
+	errs, _ := c.Validate().(ValidationError)
+
+	fields := map[string]bool{}
+	for _, fe := range errs {
+		fields[fe.Field] = true
+	}
+
+	for _, field := range []string{"HashLength", "SaltLength", "TimeCost", "MemoryCost", "Parallelism", "Mode", "Version"} {
+		if !fields[field] {
+			t.Errorf("expected Validate() to report %s, got: %v", field, errs)
+		}
+		delete(fields, field)
+
+		if _, ok := (&phcOffsets{}).of(field); !ok {
+			t.Errorf("%s: missing offset mapping", field)
+		}
+	}
+
+	// Fields added to Validate() must be added to phcOffsets.of() as well.
+	for field := range fields {
+		t.Errorf("%s isn't covered by phcOffsets", field)
+	}
+}
+
+// Ensures that DecodeStrict() only accepts canonical hashes,
+// which are reproduced exactly by Encode() and accepted by Decode().
+func FuzzDecodeStrict(f *testing.F) {
+	f.Add(expectedEncoded)
+	f.Add([]byte("$argon2i$v=16$m=8,t=1,p=1,keyid=azE,data=ZGF0YQ$c2FsdHNhbHQ$aGFzaA"))
+
+	f.Fuzz(func(t *testing.T, encoded []byte) {
+		r, err := DecodeStrict(encoded)
+		if err != nil {
+			if _, ok := err.(*DecodeError); !ok {
+				t.Fatalf("expected a DecodeError for %q, got: %v", encoded, err)
+			}
+			return
+		}
+
+		if !bytes.Equal(r.Encode(), encoded) {
+			t.Fatalf("expected %s, got: %s", encoded, r.Encode())
+		}
+
+		if _, err := Decode(encoded); err != nil {
+			t.Fatalf("Decode() failed for %q: %v", encoded, err)
+		}
+
+		if _, err := DecodeLenient(encoded); err != nil {
+			t.Fatalf("DecodeLenient() failed for %q: %v", encoded, err)
+		}
+	})
+}
@@ -1,3 +1,5 @@
+//go:build cgo
+
 /*
  * Argon2 reference source code package - reference C implementations
  *
@@ -351,6 +353,7 @@ int decode_string(argon2_context *ctx, const char *str, argon2_type type) {
     ctx->allocate_cbk = NULL;
     ctx->free_cbk = NULL;
     ctx->flags = ARGON2_DEFAULT_FLAGS;
+    ctx->abort_flag = NULL;
 
     /* On return, must have valid context */
     validation_result = validate_inputs(ctx);
@@ -7,6 +7,7 @@ package argon2
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/binary"
 	"strconv"
 )
 
@@ -30,6 +31,11 @@ func (p *parser) check(b []byte) int {
 	return 0
 }
 
+// Returns true if the next len(b) bytes match b, without increasing the offset.
+func (p *parser) peek(b []byte) bool {
+	return bytes.HasPrefix(p.buf[p.off:], b)
+}
+
 // Reads a single byte or returns 0
 func (p *parser) readByte() byte {
 	if p.off < len(p.buf) {
@@ -92,6 +98,24 @@ func (p *parser) readSlice(delim byte) []byte {
 	return nil
 }
 
+// Returns the bytes up to the next ',' or '$' without consuming the delimiter.
+// Returns nil if the slice length is less than 1.
+func (p *parser) readParam() []byte {
+	i := p.off
+	j := i
+
+	for j < len(p.buf) && p.buf[j] != ',' && p.buf[j] != '$' {
+		j++
+	}
+
+	if j > i {
+		p.off = j
+		return p.buf[i:j]
+	}
+
+	return nil
+}
+
 // Returns the rest of the parser buffer as a slice, or nil
 // if the length of the slice is less than 1.
 func (p *parser) readRest() []byte {
@@ -144,18 +168,47 @@ var (
 	decChunk3 = []byte("$m=")
 	decChunk4 = []byte(",t=")
 	decChunk5 = []byte(",p=")
+	decChunk6 = []byte(",data=")
+	decChunk7 = []byte(",keyid=")
 	encTypD   = []byte("d$v=")
 	encTypI   = []byte("i$v=")
 	encTypID  = []byte("id$v=")
 )
 
+// encodable returns true if `raw` can be represented by Encode() and Decode(),
+// which isn't the case for incomplete Raw structs, like the zero value.
+func (raw *Raw) encodable() bool {
+	c := &raw.Config
+	return c.Mode <= ModeArgon2id && c.Version != 0 && c.Version <= 255 &&
+		c.MemoryCost != 0 && c.TimeCost != 0 && c.Parallelism != 0 &&
+		len(raw.Salt) != 0 && len(raw.Hash) != 0 &&
+		uint64(len(raw.Salt)) <= maxSaltLength && uint64(len(raw.Hash)) <= maxOutLength &&
+		uint64(len(raw.AssociatedData)) <= maxAdLength && uint64(len(raw.KeyID)) <= 0xFFFFFFFF
+}
+
 // Encode turns a Raw struct into the official stringified/encoded argon2 representation.
 //
 // The resulting byte slice can safely be turned into a string.
 func (raw *Raw) Encode() []byte {
+	return raw.AppendEncode(nil)
+}
+
+// AppendEncode works like Encode(), but appends the encoded representation to `dst`
+// and returns the extended buffer. If `dst` has sufficient capacity no memory is allocated.
+func (raw *Raw) AppendEncode(dst []byte) []byte {
 	c := raw.Config
 	saltLen64 := enc64.EncodedLen(len(raw.Salt))
 	hashLen64 := enc64.EncodedLen(len(raw.Hash))
+	keyIDLen64 := 0
+	dataLen64 := 0
+
+	if len(raw.KeyID) > 0 {
+		keyIDLen64 = len(decChunk7) + enc64.EncodedLen(len(raw.KeyID))
+	}
+
+	if len(raw.AssociatedData) > 0 {
+		dataLen64 = len(decChunk6) + enc64.EncodedLen(len(raw.AssociatedData))
+	}
 
 	// 36 is a good estimate for the maximal likely static overhead, based on:
 	//     7 ("$argon2") + 2 (mode)
@@ -163,9 +216,17 @@ func (raw *Raw) Encode() []byte {
 	//   + 3 ("$m=") + 7 (memory)
 	//   + 3 (",t=") + 2 (time)
 	//   + 3 (",p=") + 2 (parallelism)
+	//   + keyIDLen64 (",keyid=" + key identifier, optional)
+	//   + dataLen64 (",data=" + associated data, optional)
 	//   + 1 ("$") + saltLen64 (salt)
 	//   + 1 ("$") + hashLen64 (hash)
-	buf := make([]byte, 0, saltLen64+hashLen64+36)
+	n := saltLen64 + hashLen64 + keyIDLen64 + dataLen64 + 36
+	buf := dst
+
+	if cap(buf)-len(buf) < n {
+		buf = append(make([]byte, 0, len(buf)+n), buf...)
+	}
+
 	var encTyp []byte
 
 	switch c.Mode {
@@ -186,6 +247,17 @@ func (raw *Raw) Encode() []byte {
 	buf = strconv.AppendUint(buf, uint64(c.TimeCost), 10)
 	buf = append(buf, decChunk5...)
 	buf = strconv.AppendUint(buf, uint64(c.Parallelism), 10)
+
+	if keyIDLen64 > 0 {
+		buf = append(buf, decChunk7...)
+		buf = appendBase64(buf, raw.KeyID, keyIDLen64-len(decChunk7))
+	}
+
+	if dataLen64 > 0 {
+		buf = append(buf, decChunk6...)
+		buf = appendBase64(buf, raw.AssociatedData, dataLen64-len(decChunk6))
+	}
+
 	buf = append(buf, '$')
 	buf = appendBase64(buf, raw.Salt, saltLen64)
 	buf = append(buf, '$')
@@ -196,7 +268,11 @@ func (raw *Raw) Encode() []byte {
 
 // Decode takes a stringified/encoded argon2 hash and turns it back into a Raw struct.
 //
-// This decoder ignores "data" attributes as they are likely to be deprecated.
+// The optional "keyid" and "data" attributes are decoded into Raw.KeyID and Raw.AssociatedData.
+//
+// For compatibility Decode is permissive and e.g. ignores unknown parameters.
+// Use DecodeStrict() to enforce the PHC string format or DecodeLenient()
+// to accept legacy hashes. Both report the offset of malformed input.
 func Decode(encoded []byte) (*Raw, error) {
 	pa := parser{buf: encoded}
 
@@ -219,8 +295,10 @@ func Decode(encoded []byte) (*Raw, error) {
 			}
 		} else if typ2 == '$' {
 			mode = ModeArgon2i
+		} else {
+			return nil, ErrIncorrectType
 		}
-	} else if typ1 == 'd' {
+	} else if typ1 == 'd' && typ2 == '$' {
 		mode = ModeArgon2d
 	} else {
 		return nil, ErrIncorrectType
@@ -234,11 +312,38 @@ func Decode(encoded []byte) (*Raw, error) {
 	t := pa.parseUint32()
 	ok |= pa.check(decChunk5)
 	p := pa.parseUint32()
+	var k, d []byte
+	hasK := pa.peek(decChunk7)
+	if hasK {
+		pa.off += len(decChunk7)
+		k = pa.readParam()
+	}
+	hasD := pa.peek(decChunk6)
+	if hasD {
+		pa.off += len(decChunk6)
+		d = pa.readParam()
+	}
+	// Unknown parameters are skipped, but a known attribute which appears out of
+	// order or twice must not be, as it would be silently dropped otherwise.
+	rest := pa.buf[pa.off:]
+	if i := bytes.IndexByte(rest, '$'); i >= 0 {
+		rest = rest[:i]
+	}
+	if bytes.Contains(rest, decChunk7) || bytes.Contains(rest, decChunk6) {
+		return nil, ErrDecodingFail
+	}
 	pa.skipUntil('$')
 	s := pa.readSlice('$')
 	h := pa.readRest()
 
-	if ok != 0 || v == 0 || v > 255 || m == 0 || t == 0 || p == 0 || s == nil || h == nil {
+	if ok != 0 || v == 0 || v > 255 || m == 0 || t == 0 || p == 0 || s == nil || h == nil || (hasK && k == nil) || (hasD && d == nil) {
+		return nil, ErrDecodingFail
+	}
+
+	keyID, ke := decodeBase64(k)
+	data, de := decodeBase64(d)
+
+	if ke != nil || de != nil {
 		return nil, ErrDecodingFail
 	}
 
@@ -261,7 +366,175 @@ func Decode(encoded []byte) (*Raw, error) {
 			Mode:        mode,
 			Version:     Version(v),
 		},
-		Salt: salt[0:sl],
-		Hash: hash[0:hl],
+		Salt:           salt[0:sl],
+		Hash:           hash[0:hl],
+		KeyID:          keyID,
+		AssociatedData: data,
 	}, nil
 }
+
+// decodeBase64 decodes an optional attribute, returning nil if `src` is nil.
+func decodeBase64(src []byte) ([]byte, error) {
+	if src == nil {
+		return nil, nil
+	}
+
+	dst := make([]byte, enc64.DecodedLen(len(src)))
+	n, err := enc64.Decode(dst, src)
+	return dst[0:n], err
+}
+
+const (
+	// binaryFormat is the version of the format written by MarshalBinary().
+	binaryFormat = 1
+
+	// binaryFlagData indicates that AssociatedData follows the hash (and KeyID).
+	binaryFlagData = 1 << 0
+
+	// binaryFlagKeyID indicates that KeyID follows the hash.
+	binaryFlagKeyID = 1 << 1
+
+	binaryFlagsKnown = binaryFlagData | binaryFlagKeyID
+)
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+//
+// It returns a compact alternative to Encode() which contains the same information:
+//
+//	format version (1 byte, currently 1)
+//	flags (1 byte, bit 0: AssociatedData is present, bit 1: KeyID is present)
+//	mode (1 byte)
+//	version (1 byte)
+//	MemoryCost, TimeCost, Parallelism (uvarint each)
+//	salt length (uvarint), salt
+//	hash length (uvarint), hash
+//	key ID length (uvarint), key ID (if bit 1 of flags is set)
+//	associated data length (uvarint), associated data (if bit 0 of flags is set)
+//
+// A hash using the recommended 16 byte salt and 32 byte hash results in about 60 bytes,
+// compared to about 100 bytes of Encode(). ErrEncodingFail is returned for Raw
+// structs which can't be represented by Encode() and Decode() either.
+func (raw Raw) MarshalBinary() ([]byte, error) {
+	c := &raw.Config
+
+	if !raw.encodable() {
+		return nil, ErrEncodingFail
+	}
+
+	flags := byte(0)
+	if len(raw.AssociatedData) > 0 {
+		flags |= binaryFlagData
+	}
+	if len(raw.KeyID) > 0 {
+		flags |= binaryFlagKeyID
+	}
+
+	buf := make([]byte, 0, 4+8*binary.MaxVarintLen32+len(raw.Salt)+len(raw.Hash)+len(raw.KeyID)+len(raw.AssociatedData))
+	buf = append(buf, binaryFormat, flags, byte(c.Mode), byte(c.Version))
+	buf = binary.AppendUvarint(buf, uint64(c.MemoryCost))
+	buf = binary.AppendUvarint(buf, uint64(c.TimeCost))
+	buf = binary.AppendUvarint(buf, uint64(c.Parallelism))
+	buf = binary.AppendUvarint(buf, uint64(len(raw.Salt)))
+	buf = append(buf, raw.Salt...)
+	buf = binary.AppendUvarint(buf, uint64(len(raw.Hash)))
+	buf = append(buf, raw.Hash...)
+
+	if flags&binaryFlagKeyID != 0 {
+		buf = binary.AppendUvarint(buf, uint64(len(raw.KeyID)))
+		buf = append(buf, raw.KeyID...)
+	}
+
+	if flags&binaryFlagData != 0 {
+		buf = binary.AppendUvarint(buf, uint64(len(raw.AssociatedData)))
+		buf = append(buf, raw.AssociatedData...)
+	}
+
+	return buf, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+// It decodes the format written by MarshalBinary() and copies all data.
+//
+// ErrDecodingFail is returned for malformed or unknown data.
+func (raw *Raw) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 || data[0] != binaryFormat || data[1]&^binaryFlagsKnown != 0 {
+		return ErrDecodingFail
+	}
+
+	flags := data[1]
+	mode := Mode(data[2])
+	version := Version(data[3])
+	d := binaryDecoder{buf: data[4:]}
+
+	m := d.uvarint32()
+	t := d.uvarint32()
+	p := d.uvarint32()
+	salt := d.bytes()
+	hash := d.bytes()
+
+	var keyID, ad []byte
+	if flags&binaryFlagKeyID != 0 {
+		keyID = d.bytes()
+	}
+	if flags&binaryFlagData != 0 {
+		ad = d.bytes()
+	}
+
+	if d.err || len(d.buf) != 0 || mode > ModeArgon2id || version == 0 || m == 0 || t == 0 || p == 0 {
+		return ErrDecodingFail
+	}
+
+	*raw = Raw{
+		Config: Config{
+			HashLength:  uint32(len(hash)),
+			SaltLength:  uint32(len(salt)),
+			MemoryCost:  m,
+			TimeCost:    t,
+			Parallelism: p,
+			Mode:        mode,
+			Version:     version,
+		},
+		Salt:           salt,
+		Hash:           hash,
+		KeyID:          keyID,
+		AssociatedData: ad,
+	}
+	return nil
+}
+
+// A helper for UnmarshalBinary(). Every operation consumes bytes from buf
+// and sets err instead of returning an error, just like parser.
+type binaryDecoder struct {
+	buf []byte
+	err bool
+}
+
+// Reads a canonically encoded uvarint which fits into an uint32.
+func (d *binaryDecoder) uvarint32() uint32 {
+	v, n := binary.Uvarint(d.buf)
+
+	// Overlong encodings are rejected, as they would break the 1:1 mapping with Encode().
+	if n <= 0 || v > 0xFFFFFFFF || (n > 1 && d.buf[n-1] == 0) {
+		d.err = true
+		d.buf = nil
+		return 0
+	}
+
+	d.buf = d.buf[n:]
+	return uint32(v)
+}
+
+// Reads a length-prefixed, non-empty byte slice and returns a copy of it.
+func (d *binaryDecoder) bytes() []byte {
+	l := d.uvarint32()
+
+	if l == 0 || uint64(l) > uint64(len(d.buf)) {
+		d.err = true
+		d.buf = nil
+		return nil
+	}
+
+	b := append([]byte(nil), d.buf[:l]...)
+	d.buf = d.buf[l:]
+	return b
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRawBinary(t *testing.T) {
+	r, err := Decode(expectedEncoded)
+	mustBeFalsey(t, "err1", err)
+
+	data, err := r.MarshalBinary()
+	mustBeFalsey(t, "err2", err)
+
+	if len(data) > 4+3+1+1+1+8+1+32 {
+		t.Errorf("expected a compact encoding, got %d bytes", len(data))
+	}
+
+	var r2 Raw
+	err = r2.UnmarshalBinary(data)
+	mustBeFalsey(t, "err3", err)
+
+	if !bytes.Equal(r2.Encode(), expectedEncoded) {
+		t.Errorf("expected %s, got: %s", expectedEncoded, r2.Encode())
+	}
+
+	// The associated data must be retained.
+	r.AssociatedData = []byte("data")
+	data, err = r.MarshalBinary()
+	mustBeFalsey(t, "err4", err)
+
+	err = r2.UnmarshalBinary(data)
+	mustBeFalsey(t, "err5", err)
+
+	if !bytes.Equal(r2.AssociatedData, r.AssociatedData) {
+		t.Error("associated data does not match")
+	}
+
+	// The key identifier must be retained as well.
+	r.KeyID = []byte("k1")
+	data, err = r.MarshalBinary()
+	mustBeFalsey(t, "err6", err)
+
+	err = r2.UnmarshalBinary(data)
+	mustBeFalsey(t, "err7", err)
+
+	if !bytes.Equal(r2.KeyID, r.KeyID) || !bytes.Equal(r2.AssociatedData, r.AssociatedData) {
+		t.Error("key identifier or associated data does not match")
+	}
+
+	for _, data := range [][]byte{
+		nil,
+		{2, 0, 2, 0x13, 1, 1, 1, 1, 0, 1, 0},       // unknown format
+		{1, 0x80, 2, 0x13, 1, 1, 1, 1, 0, 1, 0},    // unknown flag
+		{1, 0, 3, 0x13, 1, 1, 1, 1, 0, 1, 0},       // unknown mode
+		{1, 0, 2, 0x13, 0, 1, 1, 1, 0, 1, 0},       // m=0
+		{1, 0, 2, 0x13, 0x81, 0, 1, 1, 1, 0, 1, 0}, // overlong m
+		{1, 0, 2, 0x13, 1, 1, 1, 0, 1, 0},          // empty salt
+		{1, 0, 2, 0x13, 1, 1, 1, 1, 0, 2, 0},       // truncated hash
+		{1, 0, 2, 0x13, 1, 1, 1, 1, 0, 1, 0, 0},    // trailing data
+		{1, 1, 2, 0x13, 1, 1, 1, 1, 0, 1, 0},       // missing associated data
+		{1, 2, 2, 0x13, 1, 1, 1, 1, 0, 1, 0, 0},    // empty key identifier
+	} {
+		if err := r2.UnmarshalBinary(data); err != ErrDecodingFail {
+			t.Errorf("%v: expected ErrDecodingFail, got: %v", data, err)
+		}
+	}
+
+	_, err = (&Raw{Config: config}).MarshalBinary()
+	if err != ErrEncodingFail {
+		t.Errorf("expected ErrEncodingFail for a Raw without hash, got: %v", err)
+	}
+}
+
+func TestRawKeyID(t *testing.T) {
+	r, err := Decode(expectedEncoded)
+	mustBeFalsey(t, "err1", err)
+
+	r.KeyID = []byte("k1")
+	r.AssociatedData = []byte("data")
+
+	encoded := r.Encode()
+	expected := []byte("$argon2id$v=19$m=32768,t=1,p=1,keyid=azE,data=ZGF0YQ$c2FsdHNhbHQ$i3ZCXD8RMwu4akQl0xCL9L3ZJjV0lIutsAO27+vSS5s")
+	if !bytes.Equal(encoded, expected) {
+		t.Errorf("expected %s, got: %s", expected, encoded)
+	}
+
+	r2, err := Decode(encoded)
+	mustBeFalsey(t, "err2", err)
+
+	if !bytes.Equal(r2.KeyID, r.KeyID) || !bytes.Equal(r2.AssociatedData, r.AssociatedData) {
+		t.Error("key identifier or associated data does not match")
+	}
+
+	// Each attribute is optional.
+	r2, err = Decode([]byte("$argon2id$v=19$m=32768,t=1,p=1,keyid=azE$c2FsdHNhbHQ$aGFzaA"))
+	mustBeFalsey(t, "err3", err)
+
+	if !bytes.Equal(r2.KeyID, r.KeyID) || r2.AssociatedData != nil {
+		t.Error("expected a key identifier without associated data")
+	}
+
+	for _, encoded := range []string{
+		"$argon2id$v=19$m=32768,t=1,p=1,keyid=$c2FsdHNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=32768,t=1,p=1,keyid=azE,data=$c2FsdHNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=32768,t=1,p=1,keyid=a!E$c2FsdHNhbHQ$aGFzaA",
+		// Known attributes must neither appear out of order nor twice.
+		"$argon2id$v=19$m=32768,t=1,p=1,data=ZGF0YQ,keyid=azE$c2FsdHNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=32768,t=1,p=1,keyid=azE,keyid=azI$c2FsdHNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=32768,t=1,p=1,data=ZGF0YQ,data=ZGF0YQ$c2FsdHNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=32768,t=1,p=1,x=1,keyid=azE$c2FsdHNhbHQ$aGFzaA",
+	} {
+		if _, err := Decode([]byte(encoded)); err != ErrDecodingFail {
+			t.Errorf("%s: expected ErrDecodingFail, got: %v", encoded, err)
+		}
+	}
+}
+
+func TestDecodeType(t *testing.T) {
+	for _, encoded := range []string{
+		"$argon2ix$v=19$m=32768,t=1,p=1$c2FsdHNhbHQ$aGFzaA",
+		"$argon2dx$v=19$m=32768,t=1,p=1$c2FsdHNhbHQ$aGFzaA",
+		"$argon2idx$v=19$m=32768,t=1,p=1$c2FsdHNhbHQ$aGFzaA",
+	} {
+		if _, err := Decode([]byte(encoded)); err != ErrIncorrectType {
+			t.Errorf("%s: expected ErrIncorrectType, got: %v", encoded, err)
+		}
+	}
+}
+
+// Ensures that every encoded hash which round-trips through Encode() and Decode()
+// round-trips through the binary format as well.
+func FuzzRawEncoding(f *testing.F) {
+	f.Add(expectedEncoded)
+	f.Add([]byte("$argon2i$v=16$m=8,t=1,p=1,data=ZGF0YQ$c2FsdHNhbHQ$aGFzaA"))
+	f.Add([]byte("$argon2id$v=19$m=8,t=1,p=1,keyid=azE,data=ZGF0YQ$c2FsdHNhbHQ$aGFzaA"))
+
+	f.Fuzz(func(t *testing.T, encoded []byte) {
+		r, err := Decode(encoded)
+		if err != nil {
+			return
+		}
+
+		// Decode() accepts some inputs which Encode() can't reproduce,
+		// like empty salts. MarshalBinary() must reject exactly those.
+		_, perr := Decode(r.Encode())
+		data, err := r.MarshalBinary()
+		if (perr == nil) != (err == nil) {
+			t.Fatalf("MarshalBinary() and Decode() disagree for %q: %v and %v", encoded, err, perr)
+		}
+		if err != nil {
+			return
+		}
+
+		var r2 Raw
+		if err := r2.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary() failed for %q: %v", encoded, err)
+		}
+
+		if !bytes.Equal(r.Encode(), r2.Encode()) {
+			t.Fatalf("expected %s, got: %s", r.Encode(), r2.Encode())
+		}
+	})
+}
+
+// Ensures that every binary hash accepted by UnmarshalBinary() round-trips through Encode() and Decode().
+func FuzzRawBinary(f *testing.F) {
+	r, _ := Decode(expectedEncoded)
+	data, _ := r.MarshalBinary()
+	f.Add(data)
+
+	r.AssociatedData = []byte("data")
+	data, _ = r.MarshalBinary()
+	f.Add(data)
+
+	r.KeyID = []byte("k1")
+	data, _ = r.MarshalBinary()
+	f.Add(data)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var r Raw
+		if err := r.UnmarshalBinary(data); err != nil {
+			return
+		}
+
+		r2, err := Decode(r.Encode())
+		if err != nil {
+			t.Fatalf("Decode() failed for %q: %v", r.Encode(), err)
+		}
+
+		data2, err := r2.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() failed for %q: %v", r.Encode(), err)
+		}
+
+		if !bytes.Equal(data, data2) {
+			t.Fatalf("expected %x, got: %x", data, data2)
+		}
+	})
+}
@@ -4,56 +4,139 @@
 
 package argon2
 
-/*
-#include <argon2.h>
-*/
-import "C"
-
 import (
 	"fmt"
 )
 
 // Error represents the error code returned by argon2.
-type Error C.int
+//
+// The values are identical to the error codes of the reference C implementation.
+type Error int
 
 func (e Error) Error() string {
-	return fmt.Sprintf("argon2: %s", C.GoString(C.argon2_error_message(C.int(e))))
+	return fmt.Sprintf("argon2: %s", e.message())
 }
 
 const (
-	ErrOutputPtrNull         = Error(C.ARGON2_OUTPUT_PTR_NULL)
-	ErrOutputTooShort        = Error(C.ARGON2_OUTPUT_TOO_SHORT)
-	ErrOutputTooLong         = Error(C.ARGON2_OUTPUT_TOO_LONG)
-	ErrPwdTooShort           = Error(C.ARGON2_PWD_TOO_SHORT)
-	ErrPwdTooLong            = Error(C.ARGON2_PWD_TOO_LONG)
-	ErrSaltTooShort          = Error(C.ARGON2_SALT_TOO_SHORT)
-	ErrSaltTooLong           = Error(C.ARGON2_SALT_TOO_LONG)
-	ErrAdTooShort            = Error(C.ARGON2_AD_TOO_SHORT)
-	ErrAdTooLong             = Error(C.ARGON2_AD_TOO_LONG)
-	ErrSecretTooShort        = Error(C.ARGON2_SECRET_TOO_SHORT)
-	ErrSecretTooLong         = Error(C.ARGON2_SECRET_TOO_LONG)
-	ErrTimeTooSmall          = Error(C.ARGON2_TIME_TOO_SMALL)
-	ErrTimeTooLarge          = Error(C.ARGON2_TIME_TOO_LARGE)
-	ErrMemoryTooLittle       = Error(C.ARGON2_MEMORY_TOO_LITTLE)
-	ErrMemoryTooMuch         = Error(C.ARGON2_MEMORY_TOO_MUCH)
-	ErrLanesTooFew           = Error(C.ARGON2_LANES_TOO_FEW)
-	ErrLanesTooMany          = Error(C.ARGON2_LANES_TOO_MANY)
-	ErrPwdPtrMismatch        = Error(C.ARGON2_PWD_PTR_MISMATCH)
-	ErrSaltPtrMismatch       = Error(C.ARGON2_SALT_PTR_MISMATCH)
-	ErrSecretPtrMismatch     = Error(C.ARGON2_SECRET_PTR_MISMATCH)
-	ErrAdPtrMismatch         = Error(C.ARGON2_AD_PTR_MISMATCH)
-	ErrMemoryAllocationError = Error(C.ARGON2_MEMORY_ALLOCATION_ERROR)
-	ErrFreeMemoryCbkNull     = Error(C.ARGON2_FREE_MEMORY_CBK_NULL)
-	ErrAllocateMemoryCbkNull = Error(C.ARGON2_ALLOCATE_MEMORY_CBK_NULL)
-	ErrIncorrectParameter    = Error(C.ARGON2_INCORRECT_PARAMETER)
-	ErrIncorrectType         = Error(C.ARGON2_INCORRECT_TYPE)
-	ErrOutPtrMismatch        = Error(C.ARGON2_OUT_PTR_MISMATCH)
-	ErrThreadsTooFew         = Error(C.ARGON2_THREADS_TOO_FEW)
-	ErrThreadsTooMany        = Error(C.ARGON2_THREADS_TOO_MANY)
-	ErrMissingArgs           = Error(C.ARGON2_MISSING_ARGS)
-	ErrEncodingFail          = Error(C.ARGON2_ENCODING_FAIL)
-	ErrDecodingFail          = Error(C.ARGON2_DECODING_FAIL)
-	ErrThreadFail            = Error(C.ARGON2_THREAD_FAIL)
-	ErrDecodingLengthFail    = Error(C.ARGON2_DECODING_LENGTH_FAIL)
-	ErrVerifyMismatch        = Error(C.ARGON2_VERIFY_MISMATCH)
+	ErrOutputPtrNull         = Error(-1)
+	ErrOutputTooShort        = Error(-2)
+	ErrOutputTooLong         = Error(-3)
+	ErrPwdTooShort           = Error(-4)
+	ErrPwdTooLong            = Error(-5)
+	ErrSaltTooShort          = Error(-6)
+	ErrSaltTooLong           = Error(-7)
+	ErrAdTooShort            = Error(-8)
+	ErrAdTooLong             = Error(-9)
+	ErrSecretTooShort        = Error(-10)
+	ErrSecretTooLong         = Error(-11)
+	ErrTimeTooSmall          = Error(-12)
+	ErrTimeTooLarge          = Error(-13)
+	ErrMemoryTooLittle       = Error(-14)
+	ErrMemoryTooMuch         = Error(-15)
+	ErrLanesTooFew           = Error(-16)
+	ErrLanesTooMany          = Error(-17)
+	ErrPwdPtrMismatch        = Error(-18)
+	ErrSaltPtrMismatch       = Error(-19)
+	ErrSecretPtrMismatch     = Error(-20)
+	ErrAdPtrMismatch         = Error(-21)
+	ErrMemoryAllocationError = Error(-22)
+	ErrFreeMemoryCbkNull     = Error(-23)
+	ErrAllocateMemoryCbkNull = Error(-24)
+	ErrIncorrectParameter    = Error(-25)
+	ErrIncorrectType         = Error(-26)
+	ErrOutPtrMismatch        = Error(-27)
+	ErrThreadsTooFew         = Error(-28)
+	ErrThreadsTooMany        = Error(-29)
+	ErrMissingArgs           = Error(-30)
+	ErrEncodingFail          = Error(-31)
+	ErrDecodingFail          = Error(-32)
+	ErrThreadFail            = Error(-33)
+	ErrDecodingLengthFail    = Error(-34)
+	ErrVerifyMismatch        = Error(-35)
+
+	// errAborted is returned by the C code if a computation was aborted.
+	// It's never returned to the user, who receives ctx.Err() instead.
+	errAborted = Error(-36)
 )
+
+// message mirrors argon2_error_message() from argon2.c.
+func (e Error) message() string {
+	switch e {
+	case 0:
+		return "OK"
+	case ErrOutputPtrNull:
+		return "Output pointer is NULL"
+	case ErrOutputTooShort:
+		return "Output is too short"
+	case ErrOutputTooLong:
+		return "Output is too long"
+	case ErrPwdTooShort:
+		return "Password is too short"
+	case ErrPwdTooLong:
+		return "Password is too long"
+	case ErrSaltTooShort:
+		return "Salt is too short"
+	case ErrSaltTooLong:
+		return "Salt is too long"
+	case ErrAdTooShort:
+		return "Associated data is too short"
+	case ErrAdTooLong:
+		return "Associated data is too long"
+	case ErrSecretTooShort:
+		return "Secret is too short"
+	case ErrSecretTooLong:
+		return "Secret is too long"
+	case ErrTimeTooSmall:
+		return "Time cost is too small"
+	case ErrTimeTooLarge:
+		return "Time cost is too large"
+	case ErrMemoryTooLittle:
+		return "Memory cost is too small"
+	case ErrMemoryTooMuch:
+		return "Memory cost is too large"
+	case ErrLanesTooFew:
+		return "Too few lanes"
+	case ErrLanesTooMany:
+		return "Too many lanes"
+	case ErrPwdPtrMismatch:
+		return "Password pointer is NULL, but password length is not 0"
+	case ErrSaltPtrMismatch:
+		return "Salt pointer is NULL, but salt length is not 0"
+	case ErrSecretPtrMismatch:
+		return "Secret pointer is NULL, but secret length is not 0"
+	case ErrAdPtrMismatch:
+		return "Associated data pointer is NULL, but ad length is not 0"
+	case ErrMemoryAllocationError:
+		return "Memory allocation error"
+	case ErrFreeMemoryCbkNull:
+		return "The free memory callback is NULL"
+	case ErrAllocateMemoryCbkNull:
+		return "The allocate memory callback is NULL"
+	case ErrIncorrectParameter:
+		return "Argon2_Context context is NULL"
+	case ErrIncorrectType:
+		return "There is no such version of Argon2"
+	case ErrOutPtrMismatch:
+		return "Output pointer mismatch"
+	case ErrThreadsTooFew:
+		return "Not enough threads"
+	case ErrThreadsTooMany:
+		return "Too many threads"
+	case ErrMissingArgs:
+		return "Missing arguments"
+	case ErrEncodingFail:
+		return "Encoding failed"
+	case ErrDecodingFail:
+		return "Decoding failed"
+	case ErrThreadFail:
+		return "Threading failure"
+	case ErrDecodingLengthFail:
+		return "Some of encoded parameters are too long or too short"
+	case ErrVerifyMismatch:
+		return "The password does not match the supplied hash"
+	case errAborted:
+		return "The computation was aborted"
+	default:
+		return "Unknown error code"
+	}
+}
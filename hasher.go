@@ -0,0 +1,406 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// HasherOptions contains the options for NewHasher().
+type HasherOptions struct {
+	// MaxIdle specifies the maximum amount of idle memory matrices kept for reuse.
+	//
+	// If 0, GOMAXPROCS is used.
+	MaxIdle int
+
+	// Prealloc specifies the amount of memory matrices which are allocated
+	// (and pre-faulted) by NewHasher() up front.
+	//
+	// Values larger than MaxIdle are capped to MaxIdle.
+	Prealloc int
+
+	// IdleTimeout specifies the duration after which idle memory matrices are freed.
+	//
+	// If 0, a timeout of 1 minute is used.
+	// If negative, idle memory matrices are never freed until Close() is called.
+	IdleTimeout time.Duration
+
+	// MaxConcurrent specifies the maximum amount of hashes computed at the same time.
+	// Further hashes wait in a queue until a running one has finished.
+	// This bounds both the amount of memory and (if cgo is enabled) OS threads in use.
+	//
+	// If 0, the amount of concurrent hashes is unlimited.
+	MaxConcurrent int
+
+	// MaxQueue specifies the maximum amount of hashes waiting for one of the
+	// MaxConcurrent slots. Once the queue is full ErrOverloaded is returned.
+	//
+	// If 0, ErrOverloaded is returned immediately if MaxConcurrent hashes are running.
+	// If negative, the queue is unbounded.
+	MaxQueue int
+
+	// QueueTimeout specifies the maximum duration a hash waits in the queue,
+	// before ErrOverloaded is returned. The deadline of the context passed
+	// to HashContext() etc. is respected as well.
+	//
+	// If 0, hashes wait until their context is done.
+	QueueTimeout time.Duration
+
+	// Limits specifies upper bounds for the parameters of hashes passed to
+	// Decode() and VerifyEncoded(), which otherwise use the pool's memory
+	// for untrusted parameters, for instance LimitsFor(c).
+	//
+	// If zero, no limits are enforced.
+	Limits Limits
+}
+
+// ErrOverloaded is returned by a Hasher if the maximum amount of concurrent
+// hashes are running and the hash couldn't be queued or waited for too long.
+// See HasherOptions.MaxConcurrent.
+var ErrOverloaded = errors.New("argon2: too many concurrent hashes")
+
+// Hasher hashes passwords using a fixed Config, while reusing the memory argon2
+// allocates for its block matrix across calls. This avoids the cost of allocating,
+// faulting in and freeing up to MemoryCost KiB of memory for every single hash.
+//
+// All methods of Config are available on a Hasher and are safe for concurrent use.
+// The Raw structs returned by it continue to use the Hasher's memory pool
+// and are subject to the same concurrency limits. See HasherOptions.MaxConcurrent.
+type Hasher struct {
+	Config
+
+	pool   *matrixPool
+	limits Limits
+}
+
+// NewHasher returns a new Hasher for the Config `c`.
+//
+// Memory matrices are allocated using c.Allocator, or malloc() if it's nil.
+// Call Close() to free all memory held by the Hasher once it's not needed anymore.
+func NewHasher(c Config, opts HasherOptions) (*Hasher, error) {
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = runtime.GOMAXPROCS(0)
+	}
+
+	if opts.Prealloc > opts.MaxIdle {
+		opts.Prealloc = opts.MaxIdle
+	}
+
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = time.Minute
+	}
+
+	allocator := c.Allocator
+	if allocator == nil {
+		allocator = MallocAllocator{}
+	}
+
+	size := c.MemorySize()
+	if size > maxAllocationSize {
+		return nil, ErrMemoryTooMuch
+	}
+
+	p := &matrixPool{
+		allocator:    allocator,
+		size:         int(size),
+		maxIdle:      opts.MaxIdle,
+		idleTimeout:  opts.IdleTimeout,
+		maxQueue:     opts.MaxQueue,
+		queueTimeout: opts.QueueTimeout,
+	}
+
+	if opts.MaxConcurrent > 0 {
+		p.slots = make(chan struct{}, opts.MaxConcurrent)
+	}
+
+	for i := 0; i < opts.Prealloc; i++ {
+		mem, err := p.allocate()
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+
+		p.put(mem)
+	}
+
+	c.Allocator = p
+
+	return &Hasher{
+		Config: c,
+		pool:   p,
+		limits: opts.Limits,
+	}, nil
+}
+
+// Decode works like Decode(), but the resulting Raw struct uses the Hasher's memory pool.
+// A *LimitError is returned if the parameters exceed HasherOptions.Limits.
+func (h *Hasher) Decode(encoded []byte) (*Raw, error) {
+	r, err := h.limits.Decode(encoded)
+	if err != nil {
+		return nil, err
+	}
+	r.Config.Allocator = h.pool
+	return r, nil
+}
+
+// VerifyEncoded works like VerifyEncoded(), but uses the Hasher's memory pool.
+func (h *Hasher) VerifyEncoded(pwd []byte, encoded []byte) (bool, error) {
+	r, err := h.Decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	return r.Verify(pwd)
+}
+
+// Close frees all idle memory matrices. Memory which is currently in use
+// is freed as soon as the corresponding hash has been computed.
+//
+// The Hasher remains usable after calling Close(), but won't reuse memory anymore.
+func (h *Hasher) Close() error {
+	return h.pool.Close()
+}
+
+type pooledMatrix struct {
+	mem   []byte
+	since time.Time
+}
+
+// matrixPool is an Allocator which keeps a bounded stack of
+// idle memory matrices of a single size for reuse.
+//
+// It also limits the amount of concurrent hashes, as every hash allocates
+// exactly one matrix before being computed and frees it afterwards.
+type matrixPool struct {
+	allocator    Allocator
+	size         int
+	maxIdle      int
+	idleTimeout  time.Duration
+	slots        chan struct{} // nil if the amount of concurrent hashes is unlimited
+	maxQueue     int
+	queueTimeout time.Duration
+
+	mu     sync.Mutex
+	idle   []pooledMatrix // sorted by .since in ascending order
+	timer  *time.Timer
+	queued int
+	closed bool
+}
+
+// Allocate implements the Allocator interface.
+func (p *matrixPool) Allocate(size int) ([]byte, error) {
+	return p.allocateContext(context.Background(), size)
+}
+
+// allocateContext implements the contextAllocator interface.
+func (p *matrixPool) allocateContext(ctx context.Context, size int) ([]byte, error) {
+	err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The budget is reserved after acquiring a slot, so that queued hashes don't hold on to it.
+	err = globalMemoryBudget.reserve(ctx, uint64(size))
+	if err != nil {
+		p.release()
+		return nil, err
+	}
+
+	mem, err := p.get(size)
+	if err != nil {
+		globalMemoryBudget.release(uint64(size))
+		p.release()
+		return nil, err
+	}
+
+	return mem, nil
+}
+
+// get returns an idle memory matrix or allocates a new one.
+func (p *matrixPool) get(size int) ([]byte, error) {
+	if size != p.size {
+		return p.allocator.Allocate(size)
+	}
+
+	p.mu.Lock()
+
+	if n := len(p.idle) - 1; n >= 0 {
+		mem := p.idle[n].mem
+		p.idle[n] = pooledMatrix{}
+		p.idle = p.idle[:n]
+		p.mu.Unlock()
+		return mem, nil
+	}
+
+	p.mu.Unlock()
+	return p.allocate()
+}
+
+// Free implements the Allocator interface.
+//
+// The memory has already been erased by argon2 and can thus be reused as is.
+func (p *matrixPool) Free(b []byte) error {
+	defer p.release()
+	defer globalMemoryBudget.release(uint64(len(b)))
+	return p.put(b)
+}
+
+// put returns a memory matrix to the pool or frees it if the pool is full.
+func (p *matrixPool) put(b []byte) error {
+	if len(b) != p.size || p.size == 0 {
+		return p.allocator.Free(b)
+	}
+
+	p.mu.Lock()
+
+	if p.closed || len(p.idle) >= p.maxIdle {
+		p.mu.Unlock()
+		return p.allocator.Free(b)
+	}
+
+	p.idle = append(p.idle, pooledMatrix{mem: b, since: time.Now()})
+
+	if p.timer == nil && p.idleTimeout > 0 {
+		p.timer = time.AfterFunc(p.idleTimeout, p.shrink)
+	}
+
+	p.mu.Unlock()
+	return nil
+}
+
+// Close frees all idle memory matrices and disables any further pooling.
+func (p *matrixPool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+
+	p.mu.Unlock()
+	return p.free(idle)
+}
+
+// acquire waits for one of the MaxConcurrent slots to become available.
+func (p *matrixPool) acquire(ctx context.Context) error {
+	if p.slots == nil {
+		return nil
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	p.mu.Lock()
+	if p.maxQueue >= 0 && p.queued >= p.maxQueue {
+		p.mu.Unlock()
+		return ErrOverloaded
+	}
+	p.queued++
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.queued--
+		p.mu.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if p.queueTimeout > 0 {
+		t := time.NewTimer(p.queueTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return ErrOverloaded
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return ErrOverloaded
+		}
+		return ctx.Err()
+	}
+}
+
+// release makes a slot acquired by acquire() available again.
+func (p *matrixPool) release() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+// allocate allocates a new memory matrix and faults in all of its pages.
+func (p *matrixPool) allocate() ([]byte, error) {
+	mem, err := p.allocator.Allocate(p.size)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := os.Getpagesize()
+	for i := 0; i < len(mem); i += pageSize {
+		mem[i] = 0
+	}
+
+	return mem, nil
+}
+
+// shrink frees all memory matrices which have been idle for longer than idleTimeout.
+func (p *matrixPool) shrink() {
+	p.mu.Lock()
+
+	if p.timer == nil {
+		p.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	n := 0
+
+	for n < len(p.idle) && now.Sub(p.idle[n].since) >= p.idleTimeout {
+		n++
+	}
+
+	expired := append([]pooledMatrix(nil), p.idle[:n]...)
+	remaining := copy(p.idle, p.idle[n:])
+
+	for i := remaining; i < len(p.idle); i++ {
+		p.idle[i] = pooledMatrix{}
+	}
+
+	p.idle = p.idle[:remaining]
+
+	if len(p.idle) > 0 {
+		p.timer.Reset(p.idleTimeout - now.Sub(p.idle[0].since))
+	} else {
+		p.timer = nil
+	}
+
+	p.mu.Unlock()
+	p.free(expired)
+}
+
+func (p *matrixPool) free(matrices []pooledMatrix) error {
+	var err error
+
+	for _, m := range matrices {
+		if ferr := p.allocator.Free(m.mem); err == nil {
+			err = ferr
+		}
+	}
+
+	return err
+}
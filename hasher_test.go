@@ -0,0 +1,189 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestHasher(t *testing.T, opts HasherOptions) (*Hasher, *countingAllocator) {
+	a := &countingAllocator{}
+	cfg := config
+	cfg.Allocator = a
+
+	h, err := NewHasher(cfg, opts)
+	mustBeFalsey(t, "err", err)
+
+	return h, a
+}
+
+func TestHasher(t *testing.T) {
+	h, a := newTestHasher(t, HasherOptions{MaxIdle: 2, Prealloc: 1})
+	defer h.Close()
+
+	if a.allocated != 1 {
+		t.Errorf("expected 1 preallocated matrix, got: %d", a.allocated)
+	}
+
+	for i := 0; i < 3; i++ {
+		r, err := h.Hash(password, salt)
+		mustBeFalsey(t, "err", err)
+
+		if !bytes.Equal(r.Hash, expectedHash) {
+			t.Error("hashes do not match")
+		}
+	}
+
+	if a.allocated != 1 {
+		t.Errorf("expected the preallocated matrix to be reused, got: %d allocations", a.allocated)
+	}
+
+	ok, err := h.VerifyEncoded(password, expectedEncoded)
+	mustBeFalsey(t, "err", err)
+	if !ok {
+		t.Error("VerifyEncoded() must succeed")
+	}
+
+	// The memory must have been erased before being returned to the pool.
+	for _, m := range h.pool.idle {
+		if !bytes.Equal(m.mem, make([]byte, len(m.mem))) {
+			t.Error("pooled memory must only contain 0x00")
+		}
+	}
+
+	h.Close()
+
+	if a.allocated != 0 {
+		t.Errorf("%d allocations were not freed by Close()", a.allocated)
+	}
+}
+
+func TestHasherLimits(t *testing.T) {
+	h, a := newTestHasher(t, HasherOptions{Limits: LimitsFor(config)})
+	defer h.Close()
+
+	_, err := h.VerifyEncoded(password, []byte("$argon2id$v=19$m=4294967295,t=1,p=16777215$c2FsdHNhbHQ$aGFzaA"))
+
+	var le *LimitError
+	if !errors.As(err, &le) {
+		t.Errorf("expected a LimitError, got: %v", err)
+	}
+
+	if a.allocated != 0 {
+		t.Errorf("expected no allocations, got: %d", a.allocated)
+	}
+}
+
+func TestHasherConcurrent(t *testing.T) {
+	h, a := newTestHasher(t, HasherOptions{MaxIdle: 2})
+	defer h.Close()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ok, err := h.VerifyEncoded(password, expectedEncoded)
+			mustBeFalsey(t, "err", err)
+			if !ok {
+				t.Error("VerifyEncoded() must succeed")
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if n := atomic.LoadInt64(&a.allocated); n > 2 {
+		t.Errorf("expected at most 2 idle matrices, got: %d", n)
+	}
+}
+
+func TestHasherIdleTimeout(t *testing.T) {
+	h, a := newTestHasher(t, HasherOptions{Prealloc: 1, IdleTimeout: 10 * time.Millisecond})
+	defer h.Close()
+
+	_, err := h.Hash(password, salt)
+	mustBeFalsey(t, "err", err)
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt64(&a.allocated) != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if n := atomic.LoadInt64(&a.allocated); n != 0 {
+		t.Errorf("expected idle matrices to be freed, got: %d", n)
+	}
+}
+
+func TestHasherMaxConcurrent(t *testing.T) {
+	h, _ := newTestHasher(t, HasherOptions{MaxConcurrent: 1, MaxQueue: 1, QueueTimeout: 10 * time.Millisecond})
+	defer h.Close()
+
+	// Occupy the only slot, just like a running hash would.
+	mem, err := h.pool.Allocate(h.pool.size)
+	mustBeFalsey(t, "err", err)
+
+	_, err = h.Hash(password, salt)
+	if err != ErrOverloaded {
+		t.Errorf("expected ErrOverloaded, got: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	h.pool.queueTimeout = 0
+
+	_, err = h.HashContext(ctx, password, salt)
+	if err != ErrOverloaded {
+		t.Errorf("expected ErrOverloaded, got: %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	_, err = h.HashContext(ctx, password, salt)
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+
+	// A queued hash must proceed once the slot is released.
+	done := make(chan error)
+	go func() {
+		_, err := h.Hash(password, salt)
+		done <- err
+	}()
+
+	for {
+		h.pool.mu.Lock()
+		queued := h.pool.queued
+		h.pool.mu.Unlock()
+
+		if queued != 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The queue is full now.
+	_, err = h.Hash(password, salt)
+	if err != ErrOverloaded {
+		t.Errorf("expected ErrOverloaded, got: %v", err)
+	}
+
+	err = h.pool.Free(mem)
+	mustBeFalsey(t, "err", err)
+	mustBeFalsey(t, "err", <-done)
+
+	if n := len(h.pool.slots); n != 0 {
+		t.Errorf("expected all slots to be released, got: %d", n)
+	}
+}
@@ -0,0 +1,125 @@
+//go:build cgo
+
+/*
+ * Argon2 reference source code package - reference C implementations
+ *
+ * Copyright 2015
+ * Daniel Dinu, Dmitry Khovratovich, Jean-Philippe Aumasson, and Samuel Neves
+ *
+ * You may use this work under the terms of a Creative Commons CC0 1.0
+ * License/Waiver or the Apache Public License 2.0, at your option. The terms of
+ * these licenses can be found at:
+ *
+ * - CC0 1.0 Universal : http://creativecommons.org/publicdomain/zero/1.0
+ * - Apache 2.0        : http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * You should have received a copy of both of these licenses along with this
+ * software. If not, they may be obtained at the above URLs.
+ */
+
+#include "core.h"
+#include "impl.h"
+
+typedef void (*fill_segment_fn)(const argon2_instance_t *instance,
+                                argon2_position_t position);
+
+/*
+ * The implementation may be changed by argon2_impl_set() while hashes are
+ * being computed. All implementations produce identical results, so
+ * it's sufficient to access these atomically.
+ */
+static fill_segment_fn fill_segment_impl = fill_segment_ref;
+static argon2_impl fill_segment_impl_id = ARGON2_IMPL_REF;
+
+static argon2_impl best_impl(void) {
+#if defined(__x86_64__) || defined(__i386__)
+    if (argon2_impl_supported(ARGON2_IMPL_AVX512)) {
+        return ARGON2_IMPL_AVX512;
+    }
+    if (argon2_impl_supported(ARGON2_IMPL_AVX2)) {
+        return ARGON2_IMPL_AVX2;
+    }
+    if (argon2_impl_supported(ARGON2_IMPL_SSE)) {
+        return ARGON2_IMPL_SSE;
+    }
+#endif
+    return ARGON2_IMPL_REF;
+}
+
+/*
+ * Picks the fastest fill_segment() implementation supported by the CPU.
+ * This runs once when the library is loaded, which ensures that the selection
+ * is finished before any hash is computed.
+ */
+__attribute__((constructor)) static void fill_segment_init(void) {
+#if defined(__x86_64__) || defined(__i386__)
+    __builtin_cpu_init();
+#endif
+    argon2_impl_set(ARGON2_IMPL_AUTO);
+}
+
+int argon2_impl_supported(argon2_impl impl) {
+    switch (impl) {
+    case ARGON2_IMPL_REF:
+        return 1;
+#if defined(__x86_64__) || defined(__i386__)
+    case ARGON2_IMPL_SSE:
+        return __builtin_cpu_supports("ssse3") != 0;
+    case ARGON2_IMPL_AVX2:
+        return __builtin_cpu_supports("avx2") != 0;
+    case ARGON2_IMPL_AVX512:
+        return __builtin_cpu_supports("avx512f") != 0;
+#endif
+    default:
+        return 0;
+    }
+}
+
+argon2_impl argon2_impl_get(void) {
+    return __atomic_load_n(&fill_segment_impl_id, __ATOMIC_RELAXED);
+}
+
+int argon2_impl_set(argon2_impl impl) {
+    fill_segment_fn fn;
+
+    if (impl == ARGON2_IMPL_AUTO) {
+        impl = best_impl();
+    }
+    if (!argon2_impl_supported(impl)) {
+        return 0;
+    }
+
+    switch (impl) {
+#if defined(__x86_64__) || defined(__i386__)
+    case ARGON2_IMPL_SSE:
+        fn = fill_segment_sse;
+        break;
+    case ARGON2_IMPL_AVX2:
+        fn = fill_segment_avx2;
+        break;
+    case ARGON2_IMPL_AVX512:
+        fn = fill_segment_avx512;
+        break;
+#endif
+    default:
+        fn = fill_segment_ref;
+        break;
+    }
+
+    __atomic_store_n(&fill_segment_impl, fn, __ATOMIC_RELAXED);
+    __atomic_store_n(&fill_segment_impl_id, impl, __ATOMIC_RELAXED);
+    return 1;
+}
+
+int argon2_impl_threading(void) {
+#if defined(ARGON2_NO_THREADS)
+    return 0;
+#else
+    return 1;
+#endif
+}
+
+void fill_segment(const argon2_instance_t *instance,
+                  argon2_position_t position) {
+    __atomic_load_n(&fill_segment_impl, __ATOMIC_RELAXED)(instance, position);
+}
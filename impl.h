@@ -0,0 +1,68 @@
+/*
+ * Argon2 reference source code package - reference C implementations
+ *
+ * Copyright 2015
+ * Daniel Dinu, Dmitry Khovratovich, Jean-Philippe Aumasson, and Samuel Neves
+ *
+ * You may use this work under the terms of a Creative Commons CC0 1.0
+ * License/Waiver or the Apache Public License 2.0, at your option. The terms of
+ * these licenses can be found at:
+ *
+ * - CC0 1.0 Universal : http://creativecommons.org/publicdomain/zero/1.0
+ * - Apache 2.0        : http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * You should have received a copy of both of these licenses along with this
+ * software. If not, they may be obtained at the above URLs.
+ */
+
+#ifndef ARGON2_IMPL_H
+#define ARGON2_IMPL_H
+
+#include "core.h"
+
+/*
+ * Identifies a fill_segment() implementation.
+ * The values must match the Backend constants in backend.go.
+ */
+typedef enum argon2_impl {
+    ARGON2_IMPL_AUTO = 0,
+    ARGON2_IMPL_REF = 1,
+    ARGON2_IMPL_SSE = 2,
+    ARGON2_IMPL_AVX2 = 3,
+    ARGON2_IMPL_AVX512 = 4
+} argon2_impl;
+
+/* Returns 1 if the implementation can be used on this CPU and 0 otherwise. */
+int argon2_impl_supported(argon2_impl impl);
+
+/* Returns the implementation currently in use by fill_segment(). */
+argon2_impl argon2_impl_get(void);
+
+/*
+ * Forces fill_segment() to use the given implementation, or the fastest
+ * supported one if ARGON2_IMPL_AUTO is given. Returns 0 if the implementation
+ * isn't supported, in which case the current one is left unchanged.
+ */
+int argon2_impl_set(argon2_impl impl);
+
+/* Returns 1 if argon2_ctx() computes lanes on multiple threads. */
+int argon2_impl_threading(void);
+
+/*
+ * fill_segment() implementations. fill_segment_ref() is always available,
+ * while the SIMD variants are only compiled on x86 and may only be called
+ * if the CPU supports the respective instruction set.
+ */
+void fill_segment_ref(const argon2_instance_t *instance,
+                      argon2_position_t position);
+
+#if defined(__x86_64__) || defined(__i386__)
+void fill_segment_sse(const argon2_instance_t *instance,
+                      argon2_position_t position);
+void fill_segment_avx2(const argon2_instance_t *instance,
+                       argon2_position_t position);
+void fill_segment_avx512(const argon2_instance_t *instance,
+                         argon2_position_t position);
+#endif
+
+#endif
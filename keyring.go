@@ -0,0 +1,115 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"errors"
+)
+
+// ErrUnknownKeyID is returned if the key identifier of a hash isn't part of the Keyring.
+var ErrUnknownKeyID = errors.New("argon2: unknown key identifier")
+
+// Keyring contains the secrets ("peppers") used for keyed hashing, indexed by
+// their identifier. It allows you to rotate secrets: New hashes use the
+// Current key, while existing hashes are verified using the key
+// recorded in their Raw.KeyID, which is stored in the "keyid" attribute.
+type Keyring struct {
+	// Keys maps key identifiers to secrets.
+	Keys map[string][]byte
+
+	// Current is the identifier of the key used for new hashes.
+	// It must be present in Keys.
+	Current string
+}
+
+// secret returns a copy of the secret for `keyID`. An empty `keyID` denotes a hash without secret.
+//
+// The copy ensures that FlagClearSecret never erases the secrets in k.Keys.
+// Callers must erase it using SecureZeroMemory() once they're done.
+func (k *Keyring) secret(keyID []byte) ([]byte, error) {
+	if len(keyID) == 0 {
+		return nil, nil
+	}
+
+	secret, ok := k.Keys[string(keyID)]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+
+	return append([]byte(nil), secret...), nil
+}
+
+// HashWithKey works like HashWithData(), but additionally records `keyID` in the resulting Raw.
+// The key identifier doesn't affect the hash, but allows you to find the `secret`
+// again during verification. See Keyring.
+func (c *Config) HashWithKey(pwd []byte, salt []byte, keyID []byte, secret []byte, ad []byte) (*Raw, error) {
+	r, err := c.HashWithData(pwd, salt, secret, ad)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keyID) != 0 {
+		r.KeyID = append([]byte(nil), keyID...)
+	}
+
+	return r, nil
+}
+
+// HashWithKeyring works like HashWithKey() using the current key of `k`.
+//
+// ErrUnknownKeyID is returned if k.Current is empty or isn't part of k.Keys.
+func (c *Config) HashWithKeyring(pwd []byte, salt []byte, k *Keyring, ad []byte) (*Raw, error) {
+	if len(k.Current) == 0 {
+		return nil, ErrUnknownKeyID
+	}
+
+	secret, err := k.secret([]byte(k.Current))
+	if err != nil {
+		return nil, err
+	}
+	defer SecureZeroMemory(secret)
+
+	return c.HashWithKey(pwd, salt, []byte(k.Current), secret, ad)
+}
+
+// VerifyWithKeyring works like VerifyWithSecret(), but uses the secret in `k`
+// identified by raw.KeyID. Hashes without KeyID are verified without secret.
+//
+// ErrUnknownKeyID is returned if the key identifier isn't part of `k`.
+func (raw *Raw) VerifyWithKeyring(pwd []byte, k *Keyring) (bool, error) {
+	return raw.VerifyWithKeyringData(pwd, k, raw.AssociatedData)
+}
+
+// VerifyWithKeyringData works like VerifyWithKeyring(), but uses the expected
+// associated data `ad` instead of raw.AssociatedData. See VerifyWithData().
+func (raw *Raw) VerifyWithKeyringData(pwd []byte, k *Keyring, ad []byte) (bool, error) {
+	secret, err := k.secret(raw.KeyID)
+	if err != nil {
+		return false, err
+	}
+	defer SecureZeroMemory(secret)
+
+	return raw.VerifyWithData(pwd, secret, ad)
+}
+
+// VerifyEncodedWithKeyring works like VerifyEncoded(), but uses the secret in `k`
+// identified by the "keyid" attribute of `encoded`. See Raw.VerifyWithKeyring().
+func VerifyEncodedWithKeyring(pwd []byte, encoded []byte, k *Keyring) (bool, error) {
+	r, err := Decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	return r.VerifyWithKeyring(pwd, k)
+}
+
+// VerifyEncodedWithKeyringData works like VerifyEncodedWithKeyring(), but uses the
+// expected associated data `ad` instead of the "data" attribute of `encoded`.
+func VerifyEncodedWithKeyringData(pwd []byte, encoded []byte, k *Keyring, ad []byte) (bool, error) {
+	r, err := Decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	return r.VerifyWithKeyringData(pwd, k, ad)
+}
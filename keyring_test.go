@@ -0,0 +1,133 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKeyring(t *testing.T) {
+	k := &Keyring{
+		Keys: map[string][]byte{
+			"k1": []byte("secret1"),
+			"k2": []byte("secret2"),
+		},
+		Current: "k1",
+	}
+
+	r, err := config.HashWithKeyring(password, salt, k, nil)
+	mustBeFalsey(t, "err1", err)
+
+	if !bytes.Equal(r.KeyID, []byte("k1")) {
+		t.Errorf("expected key identifier k1, got: %s", r.KeyID)
+	}
+
+	// Rotating the current key must not affect existing hashes.
+	k.Current = "k2"
+	encoded := r.Encode()
+
+	ok, err := VerifyEncodedWithKeyring(password, encoded, k)
+	mustBeFalsey(t, "err2", err)
+	if !ok {
+		t.Error("VerifyEncodedWithKeyring() must succeed using the recorded key")
+	}
+
+	ok, err = VerifyEncoded(password, encoded)
+	mustBeFalsey(t, "err3", err)
+	if ok {
+		t.Error("VerifyEncoded() must fail without the secret")
+	}
+
+	// Hashes without key identifier are verified without secret.
+	ok, err = VerifyEncodedWithKeyring(password, expectedEncoded, k)
+	mustBeFalsey(t, "err4", err)
+	if !ok {
+		t.Error("VerifyEncodedWithKeyring() must succeed for hashes without key identifier")
+	}
+
+	delete(k.Keys, "k1")
+	_, err = VerifyEncodedWithKeyring(password, encoded, k)
+	if err != ErrUnknownKeyID {
+		t.Errorf("expected ErrUnknownKeyID, got: %v", err)
+	}
+
+	for _, current := range []string{"k3", ""} {
+		_, err = config.HashWithKeyring(password, salt, &Keyring{Keys: k.Keys, Current: current}, nil)
+		if err != ErrUnknownKeyID {
+			t.Errorf("%q: expected ErrUnknownKeyID, got: %v", current, err)
+		}
+	}
+}
+
+func TestKeyringData(t *testing.T) {
+	k := &Keyring{Keys: map[string][]byte{"k1": []byte("secret1")}, Current: "k1"}
+
+	r, err := config.HashWithKeyring(password, salt, k, []byte("user1"))
+	mustBeFalsey(t, "err1", err)
+
+	// A hash copied to a different user must not verify.
+	r.AssociatedData = []byte("user2")
+	encoded := r.Encode()
+
+	ok, err := VerifyEncodedWithKeyring(password, encoded, k)
+	mustBeFalsey(t, "err2", err)
+	if ok {
+		t.Error("VerifyEncodedWithKeyring() must fail using the stored associated data")
+	}
+
+	ok, err = VerifyEncodedWithKeyringData(password, encoded, k, []byte("user1"))
+	mustBeFalsey(t, "err3", err)
+	if !ok {
+		t.Error("VerifyEncodedWithKeyringData() must succeed using the expected associated data")
+	}
+
+	ok, err = r.VerifyWithKeyringData(password, k, []byte("user2"))
+	mustBeFalsey(t, "err4", err)
+	if ok {
+		t.Error("VerifyWithKeyringData() must fail using the wrong associated data")
+	}
+}
+
+func TestHashWithKeyCopiesKeyID(t *testing.T) {
+	keyID := []byte("k1")
+
+	r, err := config.HashWithKey(password, salt, keyID, []byte("secret1"), nil)
+	mustBeFalsey(t, "err", err)
+
+	keyID[0] = 'x'
+	if !bytes.Equal(r.KeyID, []byte("k1")) {
+		t.Errorf("expected key identifier k1, got: %s", r.KeyID)
+	}
+}
+
+// FlagClearSecret must never erase the secrets stored in a Keyring.
+func TestKeyringFlagClear(t *testing.T) {
+	k := &Keyring{Keys: map[string][]byte{"k1": []byte("secret1")}, Current: "k1"}
+
+	c := config
+	c.Flags = FlagClearPassword | FlagClearSecret
+
+	r, err := c.HashWithKeyring(append([]byte(nil), password...), salt, k, nil)
+	mustBeFalsey(t, "err1", err)
+
+	if !bytes.Equal(k.Keys["k1"], []byte("secret1")) {
+		t.Fatalf("HashWithKeyring() must not erase the secret, got: %q", k.Keys["k1"])
+	}
+
+	// Erasing the inputs of the verification must not affect the Keyring either.
+	r.Config.Flags = c.Flags
+	for i := 0; i < 2; i++ {
+		ok, err := r.VerifyWithKeyring(append([]byte(nil), password...), k)
+		mustBeFalsey(t, "err2", err)
+		if !ok {
+			t.Error("VerifyWithKeyring() must succeed using the retained secret")
+		}
+	}
+
+	if !bytes.Equal(k.Keys["k1"], []byte("secret1")) {
+		t.Errorf("VerifyWithKeyring() must not erase the secret, got: %q", k.Keys["k1"])
+	}
+}
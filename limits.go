@@ -0,0 +1,197 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"context"
+	"fmt"
+)
+
+// Limits contains upper bounds for the parameters of untrusted encoded hashes.
+//
+// Decode() accepts any parameters and a corrupted or attacker-controlled hash
+// like "m=4294967295,p=16777215" would otherwise cause verification to allocate
+// terabytes of memory or start millions of threads. A field of 0 disables the respective limit.
+//
+// The methods of Limits mirror the package level Decode*() and VerifyEncoded*() functions.
+// Hashes obtained otherwise, e.g. using Raw.Scan() or Raw.UnmarshalJSON(), can be
+// restricted using Apply().
+type Limits struct {
+	// MaxMemoryCost is the maximum MemoryCost in KiB.
+	MaxMemoryCost uint32
+
+	// MaxTimeCost is the maximum TimeCost.
+	MaxTimeCost uint32
+
+	// MaxParallelism is the maximum Parallelism.
+	MaxParallelism uint32
+
+	// MaxSaltLength is the maximum salt length in bytes.
+	MaxSaltLength uint32
+
+	// MaxHashLength is the maximum hash length in bytes.
+	MaxHashLength uint32
+}
+
+// LimitsFor returns Limits which admit hashes using up to 4 times the costs of `c`,
+// which leaves room for increasing the costs of `c` later on.
+func LimitsFor(c Config) Limits {
+	mul := func(v uint32) uint32 {
+		if v > 0xFFFFFFFF/4 {
+			return 0xFFFFFFFF
+		}
+		return v * 4
+	}
+
+	return Limits{
+		MaxMemoryCost:  mul(c.MemoryCost),
+		MaxTimeCost:    mul(c.TimeCost),
+		MaxParallelism: mul(c.Parallelism),
+		MaxSaltLength:  mul(c.SaltLength),
+		MaxHashLength:  mul(c.HashLength),
+	}
+}
+
+// LimitError is returned if a parameter of a hash exceeds its Limits.
+type LimitError struct {
+	// Field is the name of the Config field, e.g. "MemoryCost".
+	Field string
+
+	// Value is the value of the field.
+	Value uint32
+
+	// Limit is the maximum permitted value.
+	Limit uint32
+}
+
+// Error implements the error interface.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("argon2: %s of %d exceeds the limit of %d", e.Field, e.Value, e.Limit)
+}
+
+// Check returns a *LimitError if any parameter of `c` exceeds the limits.
+func (l Limits) Check(c *Config) error {
+	for _, f := range []struct {
+		field string
+		value uint32
+		limit uint32
+	}{
+		{"MemoryCost", c.MemoryCost, l.MaxMemoryCost},
+		{"TimeCost", c.TimeCost, l.MaxTimeCost},
+		{"Parallelism", c.Parallelism, l.MaxParallelism},
+		{"SaltLength", c.SaltLength, l.MaxSaltLength},
+		{"HashLength", c.HashLength, l.MaxHashLength},
+	} {
+		if f.limit != 0 && f.value > f.limit {
+			return &LimitError{Field: f.field, Value: f.value, Limit: f.limit}
+		}
+	}
+	return nil
+}
+
+// Apply returns a *LimitError if the parameters of `raw` exceed the limits.
+// The limits are recorded in `raw` either way and all of its Verify*() methods
+// check them again before computing a hash, returning a *LimitError as well.
+func (l Limits) Apply(raw *Raw) error {
+	raw.limits = &l
+	return l.Check(&raw.Config)
+}
+
+// Decode works like Decode(), but returns a *LimitError if the parameters of `encoded`
+// exceed the limits. The limits are recorded in the resulting Raw. See Apply().
+func (l Limits) Decode(encoded []byte) (*Raw, error) {
+	return l.decode(Decode, encoded)
+}
+
+// DecodeStrict works like Limits.Decode() using DecodeStrict().
+func (l Limits) DecodeStrict(encoded []byte) (*Raw, error) {
+	return l.decode(DecodeStrict, encoded)
+}
+
+// DecodeLenient works like Limits.Decode() using DecodeLenient().
+func (l Limits) DecodeLenient(encoded []byte) (*Raw, error) {
+	return l.decode(DecodeLenient, encoded)
+}
+
+func (l Limits) decode(decode func([]byte) (*Raw, error), encoded []byte) (*Raw, error) {
+	r, err := decode(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.Apply(r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// VerifyEncoded works like VerifyEncoded(), but returns a *LimitError
+// without computing a hash if the parameters of `encoded` exceed the limits.
+func (l Limits) VerifyEncoded(pwd []byte, encoded []byte) (bool, error) {
+	return l.VerifyEncodedWithSecret(pwd, encoded, nil)
+}
+
+// VerifyEncodedWithSecret works like VerifyEncodedWithSecret(), but returns a *LimitError
+// without computing a hash if the parameters of `encoded` exceed the limits.
+func (l Limits) VerifyEncodedWithSecret(pwd []byte, encoded []byte, secret []byte) (bool, error) {
+	r, err := l.Decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	return r.VerifyWithSecret(pwd, secret)
+}
+
+// VerifyEncodedWithData works like VerifyEncodedWithData(), but returns a *LimitError
+// without computing a hash if the parameters of `encoded` exceed the limits.
+func (l Limits) VerifyEncodedWithData(pwd []byte, encoded []byte, secret []byte, ad []byte) (bool, error) {
+	r, err := l.Decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	return r.VerifyWithData(pwd, secret, ad)
+}
+
+// VerifyEncodedContext works like VerifyEncodedContext(), but returns a *LimitError
+// without computing a hash if the parameters of `encoded` exceed the limits.
+func (l Limits) VerifyEncodedContext(ctx context.Context, pwd []byte, encoded []byte) (bool, error) {
+	r, err := l.Decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	return r.VerifyContext(ctx, pwd)
+}
+
+// VerifyEncodedWithKeyring works like VerifyEncodedWithKeyring(), but returns a *LimitError
+// without computing a hash if the parameters of `encoded` exceed the limits.
+func (l Limits) VerifyEncodedWithKeyring(pwd []byte, encoded []byte, k *Keyring) (bool, error) {
+	r, err := l.Decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	return r.VerifyWithKeyring(pwd, k)
+}
+
+// VerifyEncodedWithKeyringData works like VerifyEncodedWithKeyringData(), but returns a
+// *LimitError without computing a hash if the parameters of `encoded` exceed the limits.
+func (l Limits) VerifyEncodedWithKeyringData(pwd []byte, encoded []byte, k *Keyring, ad []byte) (bool, error) {
+	r, err := l.Decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	return r.VerifyWithKeyringData(pwd, k, ad)
+}
+
+// VerifyAndUpgrade works like c.VerifyAndUpgrade(), but returns a *LimitError
+// without computing a hash if the parameters of `encoded` exceed the limits.
+func (l Limits) VerifyAndUpgrade(c *Config, pwd []byte, encoded []byte) (ok bool, upgraded []byte, err error) {
+	return l.VerifyAndUpgradeWithSecret(c, pwd, encoded, nil)
+}
+
+// VerifyAndUpgradeWithSecret works like c.VerifyAndUpgradeWithSecret(), but returns a
+// *LimitError without computing a hash if the parameters of `encoded` exceed the limits.
+func (l Limits) VerifyAndUpgradeWithSecret(c *Config, pwd []byte, encoded []byte, secret []byte) (ok bool, upgraded []byte, err error) {
+	return c.verifyAndUpgrade(pwd, encoded, secret, l.Decode)
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	l := LimitsFor(config)
+
+	ok, err := l.VerifyEncoded(password, expectedEncoded)
+	mustBeFalsey(t, "err1", err)
+	if !ok {
+		t.Error("VerifyEncoded() must succeed within the limits")
+	}
+
+	for _, tc := range []struct {
+		encoded string
+		field   string
+	}{
+		{"$argon2id$v=19$m=4294967295,t=1,p=16777215$c2FsdHNhbHQ$aGFzaA", "MemoryCost"},
+		{"$argon2id$v=19$m=32768,t=100,p=1$c2FsdHNhbHQ$aGFzaA", "TimeCost"},
+		{"$argon2id$v=19$m=32768,t=1,p=16777215$c2FsdHNhbHQ$aGFzaA", "Parallelism"},
+	} {
+		_, err := l.VerifyEncoded(password, []byte(tc.encoded))
+
+		var le *LimitError
+		if !errors.As(err, &le) || le.Field != tc.field {
+			t.Errorf("%s: expected a LimitError for %s, got: %v", tc.encoded, tc.field, err)
+		}
+	}
+
+	r := Raw{Config: config}
+	r.Config.SaltLength = 65
+	if err := l.Check(&r.Config); err == nil || err.(*LimitError).Field != "SaltLength" {
+		t.Errorf("expected a LimitError for SaltLength, got: %v", err)
+	}
+
+	r.Config = config
+	r.Config.HashLength = 129
+	if err := l.Check(&r.Config); err == nil || err.(*LimitError).Field != "HashLength" {
+		t.Errorf("expected a LimitError for HashLength, got: %v", err)
+	}
+
+	// Every verification path must check the limits before computing a hash.
+	huge := []byte("$argon2id$v=19$m=4294967295,t=1,p=16777215$c2FsdHNhbHQ$aGFzaA")
+	k := &Keyring{}
+
+	for name, verify := range map[string]func() error{
+		"DecodeStrict": func() error {
+			_, err := l.DecodeStrict(huge)
+			return err
+		},
+		"DecodeLenient": func() error {
+			_, err := l.DecodeLenient(huge)
+			return err
+		},
+		"VerifyEncodedWithData": func() error {
+			_, err := l.VerifyEncodedWithData(password, huge, nil, nil)
+			return err
+		},
+		"VerifyEncodedContext": func() error {
+			_, err := l.VerifyEncodedContext(context.Background(), password, huge)
+			return err
+		},
+		"VerifyEncodedWithKeyring": func() error {
+			_, err := l.VerifyEncodedWithKeyring(password, huge, k)
+			return err
+		},
+		"VerifyEncodedWithKeyringData": func() error {
+			_, err := l.VerifyEncodedWithKeyringData(password, huge, k, nil)
+			return err
+		},
+		"VerifyAndUpgrade": func() error {
+			_, _, err := l.VerifyAndUpgrade(&config, password, huge)
+			return err
+		},
+		"VerifyAndUpgradeWithSecret": func() error {
+			_, _, err := l.VerifyAndUpgradeWithSecret(&config, password, huge, nil)
+			return err
+		},
+	} {
+		var le *LimitError
+		if err := verify(); !errors.As(err, &le) {
+			t.Errorf("%s: expected a LimitError, got: %v", name, err)
+		}
+	}
+
+	ok, _, err = l.VerifyAndUpgrade(&config, password, expectedEncoded)
+	mustBeFalsey(t, "err2", err)
+	if !ok {
+		t.Error("VerifyAndUpgrade() must succeed within the limits")
+	}
+
+	// Applied limits are checked again by Raw.verify().
+	var raw Raw
+	mustBeFalsey(t, "err3", raw.UnmarshalText(expectedEncoded))
+	mustBeFalsey(t, "err4", l.Apply(&raw))
+
+	raw.Config.TimeCost = 1000
+	if _, err := raw.VerifyContext(context.Background(), password); err == nil || err.(*LimitError).Field != "TimeCost" {
+		t.Errorf("expected a LimitError for TimeCost, got: %v", err)
+	}
+
+	// The zero value doesn't limit anything.
+	r.Config.MemoryCost = maxMemory
+	mustBeFalsey(t, "err5", Limits{}.Check(&r.Config))
+}
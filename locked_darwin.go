@@ -0,0 +1,22 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"syscall"
+)
+
+func mlock(b []byte) error {
+	return syscall.Mlock(b)
+}
+
+func mprotectNone(b []byte) error {
+	return syscall.Mprotect(b, syscall.PROT_NONE)
+}
+
+// dontDump is a no-op, as macOS has no equivalent to MADV_DONTDUMP.
+func dontDump(b []byte) error {
+	return nil
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"syscall"
+)
+
+// madvDontDump is MADV_DONTDUMP, which isn't defined by syscall on all architectures.
+const madvDontDump = 0x10
+
+func mlock(b []byte) error {
+	return syscall.Mlock(b)
+}
+
+func mprotectNone(b []byte) error {
+	return syscall.Mprotect(b, syscall.PROT_NONE)
+}
+
+func dontDump(b []byte) error {
+	return syscall.Madvise(b, madvDontDump)
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"syscall"
+	"testing"
+)
+
+// rlimitMemlock is RLIMIT_MEMLOCK, which isn't defined by syscall.
+const rlimitMemlock = 8
+
+func TestLockedBufferFallback(t *testing.T) {
+	var old syscall.Rlimit
+	if err := syscall.Getrlimit(rlimitMemlock, &old); err != nil {
+		t.Skip(err)
+	}
+
+	lim := old
+	lim.Cur = 0
+	if err := syscall.Setrlimit(rlimitMemlock, &lim); err != nil {
+		t.Skip(err)
+	}
+	defer syscall.Setrlimit(rlimitMemlock, &old)
+
+	var warnings []*LockError
+	SetLockWarning(func(err *LockError) {
+		warnings = append(warnings, err)
+	})
+	defer SetLockWarning(nil)
+
+	b, err := NewLockedBuffer(16)
+	mustBeFalsey(t, "err", err)
+	defer b.Destroy()
+
+	// Processes with CAP_IPC_LOCK (e.g. root) aren't subject to RLIMIT_MEMLOCK.
+	if b.Locked() {
+		t.Skip("mlock() succeeded despite RLIMIT_MEMLOCK of 0")
+	}
+
+	if len(warnings) != 1 || warnings[0].Op != "mlock" {
+		t.Errorf("expected a single mlock warning, got: %v", warnings)
+	}
+
+	// The unlocked buffer must remain usable.
+	copy(b, password)
+	if string(b[:len(password)]) != string(password) {
+		t.Error("buffer is not usable")
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || linux
+
+package argon2
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// LockError is reported if memory couldn't be locked into RAM or excluded from core dumps.
+// See SetLockWarning.
+type LockError struct {
+	// Op is the failed operation, e.g. "mlock".
+	Op string
+
+	// Size is the amount of memory in bytes which was supposed to be locked.
+	Size int
+
+	// Err is the error returned by the operating system.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *LockError) Error() string {
+	return fmt.Sprintf("argon2: %s of %d bytes failed, continuing with unprotected memory: %v", e.Op, e.Size, e.Err)
+}
+
+// Unwrap returns e.Err, which allows using errors.Is(err, syscall.ENOMEM) etc.
+func (e *LockError) Unwrap() error {
+	return e.Err
+}
+
+var lockWarning struct {
+	sync.Mutex
+	fn     func(err *LockError)
+	custom bool
+	logged bool
+}
+
+// SetLockWarning sets the function which is called with a *LockError if LockedBuffer
+// or LockedAllocator couldn't protect their memory and continue using unprotected memory.
+// This usually happens if RLIMIT_MEMLOCK is too low for mlock() (see "ulimit -l").
+//
+// By default the first warning is written using the log package.
+// If `fn` is nil, warnings are discarded.
+func SetLockWarning(fn func(err *LockError)) {
+	lockWarning.Lock()
+	lockWarning.fn = fn
+	lockWarning.custom = true
+	lockWarning.Unlock()
+}
+
+func reportLockWarning(err *LockError) {
+	lockWarning.Lock()
+	fn := lockWarning.fn
+	if !lockWarning.custom && !lockWarning.logged {
+		lockWarning.logged = true
+		fn = func(err *LockError) {
+			log.Print(err)
+		}
+	}
+	lockWarning.Unlock()
+
+	if fn != nil {
+		fn(err)
+	}
+}
+
+// LockedBuffer holds sensitive data like passwords in memory which is
+// locked into RAM using mlock(), so that it never reaches swap, and
+// which is excluded from core dumps using MADV_DONTDUMP (only on Linux).
+// The buffer is surrounded by inaccessible guard pages and ends right
+// before the trailing one, which makes out of bounds accesses fault.
+//
+// LockedBuffer is only available on Linux and macOS, as the syscall
+// package doesn't provide mlock() and mprotect() on the BSDs.
+//
+// A LockedBuffer is a []byte and can be passed to Hash(), Verify() etc. as is.
+// It must be released by calling Destroy() on the original slice and MUST NOT
+// be appended to, as this would move its contents onto the Go heap.
+//
+// If the memory can't be locked, for instance because RLIMIT_MEMLOCK is too low,
+// the buffer is used without being locked and a warning is reported. See SetLockWarning.
+type LockedBuffer []byte
+
+// NewLockedBuffer returns a zeroed LockedBuffer of `size` bytes.
+func NewLockedBuffer(size int) (LockedBuffer, error) {
+	if size <= 0 {
+		return nil, ErrIncorrectParameter
+	}
+	return lockedMmap(size)
+}
+
+// NewLockedBufferFrom returns a LockedBuffer containing a copy of `src`
+// and erases `src` using SecureZeroMemory() afterwards.
+func NewLockedBufferFrom(src []byte) (LockedBuffer, error) {
+	b, err := NewLockedBuffer(len(src))
+	if err != nil {
+		return nil, err
+	}
+
+	copy(b, src)
+	SecureZeroMemory(src)
+	return b, nil
+}
+
+// Locked returns true if the buffer was successfully locked into RAM.
+func (b LockedBuffer) Locked() bool {
+	if cap(b) == 0 {
+		return false
+	}
+
+	lockedMappings.Lock()
+	defer lockedMappings.Unlock()
+	return lockedMappings.m[&b[:1][0]].locked
+}
+
+// Destroy erases the buffer and releases its memory.
+// The buffer MUST NOT be used afterwards.
+func (b LockedBuffer) Destroy() error {
+	if cap(b) == 0 {
+		return nil
+	}
+
+	return lockedMunmap(b, true)
+}
+
+// LockedAllocator works like MmapAllocator, but locks the memory into RAM and excludes it
+// from core dumps (only on Linux) just like LockedBuffer. This ensures that the block matrix,
+// which contains data derived from the password, never reaches swap or core dumps either.
+//
+// Every concurrently computed hash requires Config.MemorySize() bytes of RLIMIT_MEMLOCK,
+// otherwise a warning is reported and the memory is used without being locked. See SetLockWarning.
+type LockedAllocator struct{}
+
+// Allocate implements the Allocator interface.
+func (LockedAllocator) Allocate(size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, nil
+	}
+	return lockedMmap(size)
+}
+
+// Free implements the Allocator interface.
+func (LockedAllocator) Free(b []byte) error {
+	if cap(b) == 0 {
+		return nil
+	}
+	return lockedMunmap(b, false)
+}
+
+type lockedMapping struct {
+	mem    []byte
+	locked bool
+}
+
+// lockedMappings maps the first byte of each LockedBuffer to its memory mapping,
+// which includes the guard pages and is required for munmap().
+var lockedMappings struct {
+	sync.Mutex
+	m map[*byte]lockedMapping
+}
+
+// lockedMmap maps `size` bytes (rounded up to the page size) surrounded by guard pages
+// and returns a slice of `size` bytes which ends right before the trailing guard page.
+func lockedMmap(size int) ([]byte, error) {
+	page := os.Getpagesize()
+	inner := (size + page - 1) &^ (page - 1)
+
+	mem, err := syscall.Mmap(-1, 0, page+inner+page, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+
+	err = mprotectNone(mem[:page])
+	if err == nil {
+		err = mprotectNone(mem[page+inner:])
+	}
+	if err != nil {
+		syscall.Munmap(mem)
+		return nil, err
+	}
+
+	data := mem[page+inner-size : page+inner : page+inner]
+	locked := true
+
+	if err := mlock(mem[page : page+inner]); err != nil {
+		locked = false
+		reportLockWarning(&LockError{Op: "mlock", Size: size, Err: err})
+	}
+
+	if err := dontDump(mem[page : page+inner]); err != nil {
+		reportLockWarning(&LockError{Op: "madvise", Size: size, Err: err})
+	}
+
+	lockedMappings.Lock()
+	if lockedMappings.m == nil {
+		lockedMappings.m = make(map[*byte]lockedMapping)
+	}
+	lockedMappings.m[&data[0]] = lockedMapping{mem: mem, locked: locked}
+	lockedMappings.Unlock()
+
+	return data, nil
+}
+
+// lockedMunmap unmaps the memory mapping of `b`, which unlocks it as well.
+// If `wipe` is true, `b` is erased beforehand. syscall.EINVAL is returned
+// if `b` wasn't returned by lockedMmap() or has already been unmapped.
+func lockedMunmap(b []byte, wipe bool) error {
+	key := &b[:1][0]
+
+	lockedMappings.Lock()
+	m, ok := lockedMappings.m[key]
+	delete(lockedMappings.m, key)
+	lockedMappings.Unlock()
+
+	if !ok {
+		return syscall.EINVAL
+	}
+
+	if wipe {
+		SecureZeroMemory(b)
+	}
+
+	return syscall.Munmap(m.mem)
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || linux
+
+package argon2
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLockedBuffer(t *testing.T) {
+	src := append([]byte(nil), password...)
+
+	pwd, err := NewLockedBufferFrom(src)
+	mustBeFalsey(t, "err1", err)
+
+	if !bytes.Equal(src, make([]byte, len(src))) {
+		t.Error("src must only contain 0x00")
+	}
+
+	if !bytes.Equal(pwd, password) || cap(pwd) != len(password) {
+		t.Errorf("expected %q with a capacity of %d, got: %q with a capacity of %d", password, len(password), pwd, cap(pwd))
+	}
+
+	t.Logf("locked: %v", pwd.Locked())
+
+	r, err := config.Hash(pwd, salt)
+	mustBeFalsey(t, "err2", err)
+
+	if !bytes.Equal(r.Hash, expectedHash) {
+		t.Error("hashes do not match")
+	}
+
+	ok, err := r.Verify(pwd)
+	mustBeFalsey(t, "err3", err)
+	if !ok {
+		t.Error("Verify() must succeed")
+	}
+
+	mustBeFalsey(t, "err4", pwd.Destroy())
+
+	if err := pwd.Destroy(); err == nil {
+		t.Error("Destroy() must fail for destroyed buffers")
+	}
+
+	if _, err := NewLockedBuffer(0); err != ErrIncorrectParameter {
+		t.Errorf("expected ErrIncorrectParameter, got: %v", err)
+	}
+}
+
+func TestLockedAllocator(t *testing.T) {
+	testAllocator(t, LockedAllocator{})
+}
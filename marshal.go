@@ -0,0 +1,419 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MarshalText implements the encoding.TextMarshaler interface.
+// It returns the lowercase name used in encoded hashes, e.g. "argon2id".
+func (m Mode) MarshalText() ([]byte, error) {
+	switch m {
+	case ModeArgon2d, ModeArgon2i, ModeArgon2id:
+		return []byte(strings.ToLower(m.String())), nil
+	default:
+		return nil, fmt.Errorf("argon2: invalid mode %d", uint32(m))
+	}
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// It accepts the names "argon2d", "argon2i" and "argon2id", ignoring case,
+// as well as the numeric values "0", "1" and "2".
+func (m *Mode) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "argon2d", "0":
+		*m = ModeArgon2d
+	case "argon2i", "1":
+		*m = ModeArgon2i
+	case "argon2id", "2":
+		*m = ModeArgon2id
+	default:
+		return fmt.Errorf("argon2: invalid mode %q", text)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It accepts both strings (see UnmarshalText()) and numbers, like 2.
+func (m *Mode) UnmarshalJSON(data []byte) error {
+	if len(data) != 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	return m.UnmarshalText(data)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+// It returns the decimal version used in encoded hashes, e.g. "19" for Version13.
+// The zero Version, which is used by a zero Config, results in an empty string.
+func (v Version) MarshalText() ([]byte, error) {
+	switch v {
+	case 0:
+		return []byte{}, nil
+	case Version10, Version13:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	default:
+		return nil, fmt.Errorf("argon2: invalid version %d", uint32(v))
+	}
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// It accepts the decimal version used in encoded hashes ("16" and "19"),
+// the output of String() ("10" and "13"), as well as "0x10", "0x13", "1.0" and "1.3".
+// An empty string results in the zero Version.
+func (v *Version) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "":
+		*v = 0
+	case "16", "10", "0x10", "1.0":
+		*v = Version10
+	case "19", "13", "0x13", "1.3":
+		*v = Version13
+	default:
+		return fmt.Errorf("argon2: invalid version %q", text)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It accepts both strings (see UnmarshalText()) and numbers, like 19.
+func (v *Version) UnmarshalJSON(data []byte) error {
+	if len(data) != 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	return v.UnmarshalText(data)
+}
+
+// memoryCost is a MemoryCost in KiB, which is marshaled using units like "64MiB".
+type memoryCost uint32
+
+var memoryUnits = []struct {
+	suffix string
+	kib    uint64
+}{
+	{"GiB", 1024 * 1024},
+	{"MiB", 1024},
+	{"KiB", 1},
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+// It uses the largest unit which represents `m` exactly.
+func (m memoryCost) MarshalText() ([]byte, error) {
+	for _, u := range memoryUnits {
+		if m != 0 && uint64(m)%u.kib == 0 {
+			return []byte(strconv.FormatUint(uint64(m)/u.kib, 10) + u.suffix), nil
+		}
+	}
+	return []byte("0KiB"), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// It accepts integers with an optional "KiB", "MiB" or "GiB" suffix,
+// ignoring case and surrounding whitespace. Integers without a suffix are in KiB.
+func (m *memoryCost) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	kib := uint64(1)
+
+	for _, u := range memoryUnits {
+		if len(s) >= len(u.suffix) && strings.EqualFold(s[len(s)-len(u.suffix):], u.suffix) {
+			s = strings.TrimSpace(s[:len(s)-len(u.suffix)])
+			kib = u.kib
+			break
+		}
+	}
+
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || n*kib > 0xFFFFFFFF {
+		return fmt.Errorf("argon2: invalid memory cost %q", text)
+	}
+
+	*m = memoryCost(n * kib)
+	return nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It accepts both strings (see UnmarshalText()) and numbers in KiB.
+func (m *memoryCost) UnmarshalJSON(data []byte) error {
+	if len(data) != 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	return m.UnmarshalText(data)
+}
+
+// configJSON is the representation of a Config in JSON and YAML.
+type configJSON struct {
+	Mode        Mode       `json:"mode" yaml:"mode"`
+	Version     Version    `json:"version,omitempty" yaml:"version,omitempty"`
+	MemoryCost  memoryCost `json:"memory" yaml:"memory"`
+	TimeCost    uint32     `json:"time" yaml:"time"`
+	Parallelism uint32     `json:"parallelism" yaml:"parallelism"`
+	HashLength  uint32     `json:"hashLength" yaml:"hashLength"`
+	SaltLength  uint32     `json:"saltLength" yaml:"saltLength"`
+	Threads     uint32     `json:"threads,omitempty" yaml:"threads,omitempty"`
+}
+
+func (c *Config) toJSON() configJSON {
+	return configJSON{
+		Mode:        c.Mode,
+		Version:     c.Version,
+		MemoryCost:  memoryCost(c.MemoryCost),
+		TimeCost:    c.TimeCost,
+		Parallelism: c.Parallelism,
+		HashLength:  c.HashLength,
+		SaltLength:  c.SaltLength,
+		Threads:     c.Threads,
+	}
+}
+
+func (c *Config) fromJSON(j *configJSON) {
+	c.Mode = j.Mode
+	c.Version = j.Version
+	c.MemoryCost = uint32(j.MemoryCost)
+	c.TimeCost = j.TimeCost
+	c.Parallelism = j.Parallelism
+	c.HashLength = j.HashLength
+	c.SaltLength = j.SaltLength
+	c.Threads = j.Threads
+}
+
+// MarshalJSON implements the json.Marshaler interface. The result looks like:
+//
+//	{"mode":"argon2id","version":"19","memory":"64MiB","time":3,"parallelism":4,"hashLength":32,"saltLength":16}
+//
+// The Allocator and Rand aren't marshaled.
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.toJSON())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. See MarshalJSON().
+//
+// Fields missing in `data` retain their current value. This allows you to
+// start from DefaultConfig() and only override some of the parameters.
+// "memory" may be given in KiB or using units like "64MiB".
+func (c *Config) UnmarshalJSON(data []byte) error {
+	j := c.toJSON()
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c.fromJSON(&j)
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface
+// of gopkg.in/yaml.v2 and v3. See MarshalJSON().
+func (c Config) MarshalYAML() (interface{}, error) {
+	return c.toJSON(), nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface
+// of gopkg.in/yaml.v2, which v3 supports as well. See UnmarshalJSON().
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	j := c.toJSON()
+	if err := unmarshal(&j); err != nil {
+		return err
+	}
+	c.fromJSON(&j)
+	return nil
+}
+
+// Params returns the parameters of `c` in the format used by
+// encoded hashes, e.g. "m=65536,t=3,p=4". See ParseParams().
+func (c *Config) Params() string {
+	return fmt.Sprintf("m=%d,t=%d,p=%d", c.MemoryCost, c.TimeCost, c.Parallelism)
+}
+
+// ParseParams parses parameters in the format used by encoded hashes,
+// e.g. "m=65536,t=3,p=4", into the MemoryCost, TimeCost and Parallelism of `c`.
+//
+// The parameters may be given in any order and parameters which
+// are missing in `s` retain their current value.
+func (c *Config) ParseParams(s string) error {
+	var seen [3]bool
+	m, t, p := c.MemoryCost, c.TimeCost, c.Parallelism
+
+	for _, param := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return fmt.Errorf("argon2: invalid parameter %q", param)
+		}
+
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("argon2: invalid value for parameter %q", param)
+		}
+
+		var idx int
+		switch key {
+		case "m":
+			idx, m = 0, uint32(n)
+		case "t":
+			idx, t = 1, uint32(n)
+		case "p":
+			idx, p = 2, uint32(n)
+		default:
+			return fmt.Errorf("argon2: unknown parameter %q", param)
+		}
+
+		if seen[idx] {
+			return fmt.Errorf("argon2: duplicate parameter %q", key)
+		}
+		seen[idx] = true
+	}
+
+	c.MemoryCost, c.TimeCost, c.Parallelism = m, t, p
+	return nil
+}
+
+// ParamsFlag returns a flag.Value which sets the parameters of `c` using ParseParams().
+// For instance:
+//
+//	cfg := argon2.DefaultConfig()
+//	flag.Var(argon2.ParamsFlag(&cfg), "argon2", "argon2 parameters, e.g. m=65536,t=3,p=4")
+//	flag.TextVar(&cfg.Mode, "argon2-mode", cfg.Mode, "argon2 mode")
+func ParamsFlag(c *Config) flag.Value {
+	return (*paramsFlag)(c)
+}
+
+type paramsFlag Config
+
+func (f *paramsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return (*Config)(f).Params()
+}
+
+func (f *paramsFlag) Set(s string) error {
+	return (*Config)(f).ParseParams(s)
+}
+
+// ErrNullHash is wrapped by the ScanError returned by Raw.Scan() for NULL column values.
+// Use a *Raw field in your models to store hashes in nullable columns.
+var ErrNullHash = errors.New("argon2: hash is NULL")
+
+var errScanType = errors.New("argon2: unsupported column type")
+
+// ScanError is returned by Raw.Scan() if a column value can't be converted into a Raw.
+type ScanError struct {
+	// Type is the Go type of the column value, e.g. "[]uint8".
+	Type string
+
+	// Err is ErrNullHash, the error returned by Decode() or an error indicating an unsupported type.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("argon2: cannot scan %s into Raw: %s", e.Type, strings.TrimPrefix(e.Err.Error(), "argon2: "))
+}
+
+// Unwrap returns e.Err, which allows using errors.Is(err, ErrNullHash) etc.
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface using Encode().
+// ErrEncodingFail is returned for Raw structs which Decode() wouldn't accept,
+// like the zero value. See MarshalBinary().
+func (raw Raw) MarshalText() ([]byte, error) {
+	if !raw.encodable() {
+		return nil, ErrEncodingFail
+	}
+	return raw.Encode(), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface using Decode().
+func (raw *Raw) UnmarshalText(text []byte) error {
+	r, err := Decode(text)
+	if err != nil {
+		return err
+	}
+	*raw = *r
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// The Raw is marshaled as a string containing the result of Encode().
+// Like MarshalText() it returns ErrEncodingFail for incomplete Raw structs.
+func (raw Raw) MarshalJSON() ([]byte, error) {
+	if !raw.encodable() {
+		return nil, ErrEncodingFail
+	}
+	return json.Marshal(string(raw.Encode()))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface using Decode().
+// Following the convention of encoding/json, null leaves `raw` unchanged.
+func (raw *Raw) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	return raw.UnmarshalText([]byte(s))
+}
+
+// Value implements the driver.Valuer interface.
+// The Raw is stored as a string containing the result of Encode().
+// Like MarshalText() it returns ErrEncodingFail for incomplete Raw structs,
+// which Scan() couldn't read back. Use a *Raw for nullable columns.
+func (raw Raw) Value() (driver.Value, error) {
+	if !raw.encodable() {
+		return nil, ErrEncodingFail
+	}
+	return string(raw.Encode()), nil
+}
+
+// Scan implements the sql.Scanner interface using Decode().
+// It accepts string and []byte column values.
+//
+// If the value can't be decoded a *ScanError is returned.
+// NULL values result in a *ScanError wrapping ErrNullHash.
+// Like Decode() it doesn't restrict the parameters. See Limits.Apply().
+func (raw *Raw) Scan(src interface{}) error {
+	var encoded []byte
+
+	switch v := src.(type) {
+	case nil:
+		return &ScanError{Type: "NULL", Err: ErrNullHash}
+	case string:
+		encoded = []byte(v)
+	case []byte:
+		encoded = v
+	default:
+		return &ScanError{Type: fmt.Sprintf("%T", src), Err: errScanType}
+	}
+
+	// Decode() copies all data, which is required as drivers may reuse src.
+	r, err := Decode(encoded)
+	if err != nil {
+		return &ScanError{Type: fmt.Sprintf("%T", src), Err: err}
+	}
+
+	*raw = *r
+	return nil
+}
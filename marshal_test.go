@@ -0,0 +1,371 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"testing"
+
+	yamlv2 "gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+func TestModeText(t *testing.T) {
+	for _, mode := range []Mode{ModeArgon2d, ModeArgon2i, ModeArgon2id} {
+		text, err := mode.MarshalText()
+		mustBeFalsey(t, "err", err)
+
+		var m Mode
+		err = m.UnmarshalText(text)
+		mustBeFalsey(t, "err", err)
+
+		if m != mode {
+			t.Errorf("%s: expected a roundtrip, got: %s (%s)", mode, m, text)
+		}
+	}
+
+	var m Mode
+	if err := m.UnmarshalText([]byte("Argon2ID")); err != nil || m != ModeArgon2id {
+		t.Errorf("expected Argon2id, got: %s (%v)", m, err)
+	}
+	if err := m.UnmarshalText([]byte("1")); err != nil || m != ModeArgon2i {
+		t.Errorf("expected Argon2i, got: %s (%v)", m, err)
+	}
+	if err := m.UnmarshalText([]byte("argon2x")); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+	if _, err := Mode(3).MarshalText(); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestVersionText(t *testing.T) {
+	for _, tc := range []struct {
+		text     string
+		expected Version
+	}{
+		{"16", Version10},
+		{"10", Version10},
+		{"0x10", Version10},
+		{"19", Version13},
+		{"13", Version13},
+		{"1.3", Version13},
+	} {
+		var v Version
+		if err := v.UnmarshalText([]byte(tc.text)); err != nil || v != tc.expected {
+			t.Errorf("%s: expected %s, got: %s (%v)", tc.text, tc.expected, v, err)
+		}
+	}
+
+	text, err := Version13.MarshalText()
+	mustBeFalsey(t, "err", err)
+	if string(text) != "19" {
+		t.Errorf("expected 19, got: %s", text)
+	}
+
+	text, err = Version(0).MarshalText()
+	if err != nil || len(text) != 0 {
+		t.Errorf("expected an empty string, got: %q (%v)", text, err)
+	}
+
+	var v Version
+	if err := v.UnmarshalText([]byte("20")); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
+
+func TestConfigJSON(t *testing.T) {
+	data, err := json.Marshal(RFC9106SecondConfig())
+	mustBeFalsey(t, "err1", err)
+
+	expected := `{"mode":"argon2id","version":"19","memory":"64MiB","time":3,"parallelism":4,"hashLength":32,"saltLength":16}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got: %s", expected, data)
+	}
+
+	var cfg Config
+	err = json.Unmarshal(data, &cfg)
+	mustBeFalsey(t, "err2", err)
+
+	if cfg != RFC9106SecondConfig() {
+		t.Errorf("expected a roundtrip, got: %+v", cfg)
+	}
+
+	// Missing fields retain their value and memory may be given in KiB.
+	cfg = DefaultConfig()
+	err = json.Unmarshal([]byte(`{"memory":1048576,"version":19,"time":2}`), &cfg)
+	mustBeFalsey(t, "err3", err)
+
+	if cfg.MemoryCost != 1024*1024 || cfg.TimeCost != 2 || cfg.Mode != ModeArgon2id || cfg.HashLength != 32 {
+		t.Errorf("unexpected result: %+v", cfg)
+	}
+
+	// Mode and Version may be given as numbers.
+	err = json.Unmarshal([]byte(`{"mode":1,"version":16}`), &cfg)
+	mustBeFalsey(t, "err4", err)
+
+	if cfg.Mode != ModeArgon2i || cfg.Version != Version10 {
+		t.Errorf("unexpected result: %+v", cfg)
+	}
+
+	// The zero Config omits the zero Version.
+	data, err = json.Marshal(Config{})
+	mustBeFalsey(t, "err5", err)
+
+	expected = `{"mode":"argon2d","memory":"0KiB","time":0,"parallelism":0,"hashLength":0,"saltLength":0}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got: %s", expected, data)
+	}
+
+	cfg = Config{}
+	err = json.Unmarshal(data, &cfg)
+	mustBeFalsey(t, "err6", err)
+
+	if cfg != (Config{}) {
+		t.Errorf("expected a roundtrip, got: %+v", cfg)
+	}
+
+	for _, data := range []string{`{"memory":"64MB"}`, `{"memory":"4096GiB"}`, `{"mode":"foo"}`, `{"mode":3}`, `{"version":20}`} {
+		if err := json.Unmarshal([]byte(data), &cfg); err == nil {
+			t.Errorf("%s: expected an error", data)
+		}
+	}
+}
+
+func TestConfigYAML(t *testing.T) {
+	for _, lib := range []struct {
+		name      string
+		marshal   func(interface{}) ([]byte, error)
+		unmarshal func([]byte, interface{}) error
+	}{
+		{"v2", yamlv2.Marshal, yamlv2.Unmarshal},
+		{"v3", yamlv3.Marshal, yamlv3.Unmarshal},
+	} {
+		data, err := lib.marshal(RFC9106SecondConfig())
+		mustBeFalsey(t, lib.name, err)
+
+		var cfg Config
+		err = lib.unmarshal(data, &cfg)
+		mustBeFalsey(t, lib.name, err)
+
+		if cfg != RFC9106SecondConfig() {
+			t.Errorf("%s: expected a roundtrip, got: %+v (%s)", lib.name, cfg, data)
+		}
+
+		// Missing fields retain their value, while numbers and units are accepted.
+		cfg = DefaultConfig()
+		err = lib.unmarshal([]byte("mode: argon2i\nversion: 19\nmemory: 64MiB\ntime: 3\n"), &cfg)
+		mustBeFalsey(t, lib.name, err)
+
+		expected := DefaultConfig()
+		expected.Mode = ModeArgon2i
+		expected.MemoryCost = 64 * 1024
+		expected.TimeCost = 3
+
+		if cfg != expected {
+			t.Errorf("%s: expected %+v, got: %+v", lib.name, expected, cfg)
+		}
+
+		if err := lib.unmarshal([]byte("version: 20\n"), &cfg); err == nil || cfg != expected {
+			t.Errorf("%s: expected an error and no changes, got: %+v (%v)", lib.name, cfg, err)
+		}
+	}
+}
+
+func TestMemoryCostText(t *testing.T) {
+	for _, tc := range []struct {
+		kib  uint32
+		text string
+	}{
+		{0, "0KiB"},
+		{100, "100KiB"},
+		{47104, "46MiB"},
+		{2 * 1024 * 1024, "2GiB"},
+	} {
+		text, err := memoryCost(tc.kib).MarshalText()
+		if err != nil || string(text) != tc.text {
+			t.Errorf("%d: expected %s, got: %s (%v)", tc.kib, tc.text, text, err)
+		}
+
+		var m memoryCost
+		if err := m.UnmarshalText(text); err != nil || uint32(m) != tc.kib {
+			t.Errorf("%s: expected %d, got: %d (%v)", text, tc.kib, m, err)
+		}
+	}
+
+	var m memoryCost
+	if err := m.UnmarshalText([]byte(" 64 mib ")); err != nil || m != 64*1024 {
+		t.Errorf("expected 65536, got: %d (%v)", m, err)
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	cfg := DefaultConfig()
+
+	err := cfg.ParseParams("t=3,m=65536,p=4")
+	mustBeFalsey(t, "err", err)
+
+	if cfg.MemoryCost != 65536 || cfg.TimeCost != 3 || cfg.Parallelism != 4 {
+		t.Errorf("unexpected result: %+v", cfg)
+	}
+
+	if s := cfg.Params(); s != "m=65536,t=3,p=4" {
+		t.Errorf("expected m=65536,t=3,p=4, got: %s", s)
+	}
+
+	for _, s := range []string{"", "m=1,m=2", "x=1", "m=-1", "m", "m=1,,t=1"} {
+		c := cfg
+		if err := c.ParseParams(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+		if c != cfg {
+			t.Errorf("%q: the Config must not be modified on failure", s)
+		}
+	}
+}
+
+func TestParamsFlag(t *testing.T) {
+	cfg := DefaultConfig()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(ParamsFlag(&cfg), "argon2", "")
+	fs.TextVar(&cfg.Mode, "argon2-mode", cfg.Mode, "")
+
+	err := fs.Parse([]string{"-argon2", "m=65536,t=3,p=4", "-argon2-mode", "argon2i"})
+	mustBeFalsey(t, "err1", err)
+
+	if cfg.MemoryCost != 65536 || cfg.TimeCost != 3 || cfg.Parallelism != 4 || cfg.Mode != ModeArgon2i {
+		t.Errorf("unexpected result: %+v", cfg)
+	}
+
+	err = fs.Parse([]string{"-argon2", "m=foo"})
+	mustBeTruthy(t, "err2", err)
+}
+
+func TestRawText(t *testing.T) {
+	var r Raw
+	err := r.UnmarshalText(expectedEncoded)
+	mustBeFalsey(t, "err1", err)
+
+	text, err := r.MarshalText()
+	mustBeFalsey(t, "err2", err)
+
+	if !bytes.Equal(text, expectedEncoded) {
+		t.Errorf("expected %s, got: %s", expectedEncoded, text)
+	}
+
+	if err := r.UnmarshalText([]byte("$argon2id$foo")); err != ErrDecodingFail {
+		t.Errorf("expected ErrDecodingFail, got: %v", err)
+	}
+}
+
+func TestRawJSON(t *testing.T) {
+	type model struct {
+		Hash     Raw  `json:"hash"`
+		Optional *Raw `json:"optional"`
+	}
+
+	var m model
+	err := json.Unmarshal([]byte(`{"hash":"`+string(expectedEncoded)+`","optional":null}`), &m)
+	mustBeFalsey(t, "err1", err)
+	mustBeFalsey(t, "m.Optional", m.Optional)
+
+	if !bytes.Equal(m.Hash.Hash, expectedHash) {
+		t.Error("hashes do not match")
+	}
+
+	data, err := json.Marshal(m)
+	mustBeFalsey(t, "err2", err)
+
+	expected := `{"hash":"` + string(expectedEncoded) + `","optional":null}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got: %s", expected, data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"hash":42}`), &m); err == nil {
+		t.Error("expected an error for a number")
+	}
+}
+
+func TestRawSQL(t *testing.T) {
+	r, err := Decode(expectedEncoded)
+	mustBeFalsey(t, "err1", err)
+
+	var valuer driver.Valuer = *r
+	v, err := valuer.Value()
+	mustBeFalsey(t, "err2", err)
+
+	if v != string(expectedEncoded) {
+		t.Errorf("expected %s, got: %v", expectedEncoded, v)
+	}
+
+	var scanner sql.Scanner = &Raw{}
+	for _, src := range []interface{}{string(expectedEncoded), expectedEncoded} {
+		err = scanner.Scan(src)
+		mustBeFalsey(t, "err3", err)
+
+		if !bytes.Equal(scanner.(*Raw).Hash, expectedHash) {
+			t.Errorf("%T: hashes do not match", src)
+		}
+	}
+
+	var serr *ScanError
+
+	err = scanner.Scan(nil)
+	if !errors.As(err, &serr) || !errors.Is(err, ErrNullHash) {
+		t.Errorf("expected a ScanError wrapping ErrNullHash, got: %v", err)
+	}
+
+	err = scanner.Scan("foo")
+	if !errors.As(err, &serr) || !errors.Is(err, ErrIncorrectType) {
+		t.Errorf("expected a ScanError wrapping ErrIncorrectType, got: %v", err)
+	}
+
+	err = scanner.Scan(42)
+	if !errors.As(err, &serr) || serr.Type != "int" {
+		t.Errorf("expected a ScanError for an int, got: %v", err)
+	}
+}
+
+// A zero or incomplete Raw must fail to marshal instead of
+// producing a hash which can't be decoded or scanned again.
+func TestRawZero(t *testing.T) {
+	partial, err := Decode(expectedEncoded)
+	mustBeFalsey(t, "err", err)
+	partial.Salt = nil
+
+	for _, raw := range []Raw{{}, *partial} {
+		if _, err := raw.MarshalText(); err != ErrEncodingFail {
+			t.Errorf("MarshalText(): expected ErrEncodingFail, got: %v", err)
+		}
+		if _, err := json.Marshal(raw); !errors.Is(err, ErrEncodingFail) {
+			t.Errorf("MarshalJSON(): expected ErrEncodingFail, got: %v", err)
+		}
+		if _, err := raw.Value(); err != ErrEncodingFail {
+			t.Errorf("Value(): expected ErrEncodingFail, got: %v", err)
+		}
+		if _, err := raw.MarshalBinary(); err != ErrEncodingFail {
+			t.Errorf("MarshalBinary(): expected ErrEncodingFail, got: %v", err)
+		}
+	}
+
+	// Once complete, the Raw round-trips into the zero value again.
+	partial.Salt = []byte("saltsalt")
+	v, err := partial.Value()
+	mustBeFalsey(t, "err", err)
+
+	var r Raw
+	mustBeFalsey(t, "err", r.Scan(v))
+	if !bytes.Equal(r.Encode(), partial.Encode()) {
+		t.Errorf("expected %s, got: %s", partial.Encode(), r.Encode())
+	}
+}
@@ -0,0 +1,57 @@
+//go:build cgo && (386 || amd64)
+
+/*
+ * Argon2 reference source code package - reference C implementations
+ *
+ * Copyright 2015
+ * Daniel Dinu, Dmitry Khovratovich, Jean-Philippe Aumasson, and Samuel Neves
+ *
+ * You may use this work under the terms of a Creative Commons CC0 1.0
+ * License/Waiver or the Apache Public License 2.0, at your option. The terms of
+ * these licenses can be found at:
+ *
+ * - CC0 1.0 Universal : http://creativecommons.org/publicdomain/zero/1.0
+ * - Apache 2.0        : http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * You should have received a copy of both of these licenses along with this
+ * software. If not, they may be obtained at the above URLs.
+ */
+
+#if defined(__x86_64__) || defined(__i386__)
+
+/*
+ * Everything but opt.h must be declared before the target is enabled, because
+ * clang would otherwise apply the target attribute to those functions as well.
+ */
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+#include <immintrin.h>
+
+#include "argon2.h"
+#include "blake2.h"
+#include "core.h"
+#include "impl.h"
+
+#if defined(__clang__)
+#pragma clang attribute push(__attribute__((target("sse2,ssse3"))), apply_to = function)
+#else
+#pragma GCC target("sse2,ssse3")
+#endif
+
+/* clang doesn't define the feature macros for pragma-enabled targets. */
+#ifndef __SSE2__
+#define __SSE2__ 1
+#endif
+#ifndef __SSSE3__
+#define __SSSE3__ 1
+#endif
+
+#define ARGON2_FILL_SEGMENT fill_segment_sse
+#include "opt.h"
+
+#if defined(__clang__)
+#pragma clang attribute pop
+#endif
+
+#endif
@@ -0,0 +1,87 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+// RFC9106FirstConfig returns the first recommended option of RFC 9106, section 4:
+// ModeArgon2id with a TimeCost of 1, 2 GiB of memory, 4 lanes,
+// a 128-bit salt and a 256-bit hash.
+//
+// Use this option if the machine can afford 2 GiB of memory for every concurrent hash.
+//
+//	https://www.rfc-editor.org/rfc/rfc9106.html#section-4
+func RFC9106FirstConfig() Config {
+	return Config{
+		HashLength:  32,
+		SaltLength:  16,
+		TimeCost:    1,
+		MemoryCost:  2 * 1024 * 1024,
+		Parallelism: 4,
+		Mode:        ModeArgon2id,
+		Version:     Version13,
+	}
+}
+
+// RFC9106SecondConfig returns the second recommended option of RFC 9106, section 4:
+// ModeArgon2id with a TimeCost of 3, 64 MiB of memory, 4 lanes,
+// a 128-bit salt and a 256-bit hash.
+//
+// Use this option in memory-constrained environments.
+//
+//	https://www.rfc-editor.org/rfc/rfc9106.html#section-4
+func RFC9106SecondConfig() Config {
+	return Config{
+		HashLength:  32,
+		SaltLength:  16,
+		TimeCost:    3,
+		MemoryCost:  64 * 1024,
+		Parallelism: 4,
+		Mode:        ModeArgon2id,
+		Version:     Version13,
+	}
+}
+
+// The OWASP Password Storage Cheat Sheet recommends one of five equivalent
+// ModeArgon2id configurations, trading memory for time, all using a single lane:
+//
+//	https://cheatsheetseries.owasp.org/cheatsheets/Password_Storage_Cheat_Sheet.html#argon2id
+//
+// The functions below return them using a 128-bit salt and a 256-bit hash.
+
+// OWASP46MiBConfig returns the OWASP recommendation using 46 MiB of memory and a TimeCost of 1.
+func OWASP46MiBConfig() Config {
+	return owaspConfig(46*1024, 1)
+}
+
+// OWASP19MiBConfig returns the OWASP recommendation using 19 MiB of memory and a TimeCost of 2.
+func OWASP19MiBConfig() Config {
+	return owaspConfig(19*1024, 2)
+}
+
+// OWASP12MiBConfig returns the OWASP recommendation using 12 MiB of memory and a TimeCost of 3.
+func OWASP12MiBConfig() Config {
+	return owaspConfig(12*1024, 3)
+}
+
+// OWASP9MiBConfig returns the OWASP recommendation using 9 MiB of memory and a TimeCost of 4.
+func OWASP9MiBConfig() Config {
+	return owaspConfig(9*1024, 4)
+}
+
+// OWASP7MiBConfig returns the OWASP recommendation using 7 MiB of memory and a TimeCost of 5.
+func OWASP7MiBConfig() Config {
+	return owaspConfig(7*1024, 5)
+}
+
+func owaspConfig(memoryCost uint32, timeCost uint32) Config {
+	return Config{
+		HashLength:  32,
+		SaltLength:  16,
+		TimeCost:    timeCost,
+		MemoryCost:  memoryCost,
+		Parallelism: 1,
+		Mode:        ModeArgon2id,
+		Version:     Version13,
+	}
+}
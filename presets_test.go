@@ -0,0 +1,41 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"testing"
+)
+
+func TestPresets(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		cfg         Config
+		timeCost    uint32
+		memoryCost  uint32
+		parallelism uint32
+	}{
+		{"RFC9106FirstConfig", RFC9106FirstConfig(), 1, 2097152, 4},
+		{"RFC9106SecondConfig", RFC9106SecondConfig(), 3, 65536, 4},
+		{"OWASP46MiBConfig", OWASP46MiBConfig(), 1, 47104, 1},
+		{"OWASP19MiBConfig", OWASP19MiBConfig(), 2, 19456, 1},
+		{"OWASP12MiBConfig", OWASP12MiBConfig(), 3, 12288, 1},
+		{"OWASP9MiBConfig", OWASP9MiBConfig(), 4, 9216, 1},
+		{"OWASP7MiBConfig", OWASP7MiBConfig(), 5, 7168, 1},
+	} {
+		expected := Config{
+			HashLength:  32,
+			SaltLength:  16,
+			TimeCost:    tc.timeCost,
+			MemoryCost:  tc.memoryCost,
+			Parallelism: tc.parallelism,
+			Mode:        ModeArgon2id,
+			Version:     Version13,
+		}
+
+		if tc.cfg != expected {
+			t.Errorf("%s: expected %+v, got: %+v", tc.name, expected, tc.cfg)
+		}
+	}
+}
@@ -0,0 +1,469 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"context"
+	"encoding/binary"
+	"math/bits"
+	"sync"
+	"unsafe"
+)
+
+// This file contains a pure Go port of argon2_ctx() from argon2.c, core.c and ref_opt.c.
+// It's used if cgo is disabled and produces identical results to the C implementation.
+
+const (
+	qwordsInBlock    = blockSize / 8
+	addressesInBlock = 128
+	prehashDigestLen = 64
+	prehashSeedLen   = 72
+
+	// maxMemory mirrors ARGON2_MAX_MEMORY, which is 2^32-1 blocks
+	// on 64-bit platforms and 2^21 blocks on 32-bit platforms.
+	maxMemory = 1<<21 + (bits.UintSize/32-1)*(0xFFFFFFFF-1<<21)
+)
+
+// block is a 1 KiB memory block of the block matrix.
+type block [qwordsInBlock]uint64
+
+// instance mirrors argon2_instance_t.
+type instance struct {
+	memory        []block
+	version       Version
+	passes        uint32
+	memoryBlocks  uint32
+	segmentLength uint32
+	laneLength    uint32
+	lanes         uint32
+	threads       uint32
+	mode          Mode
+}
+
+// position mirrors argon2_position_t.
+type position struct {
+	pass  uint32
+	lane  uint32
+	slice uint32
+	index uint32
+}
+
+// computeGo is the pure Go equivalent of compute().
+func computeGo(ctx context.Context, c *Config, out []byte, pwd []byte, salt []byte, secret []byte, ad []byte, memory []byte) error {
+	threads := c.threads()
+
+	err := validateInputs(c, out, pwd, salt, secret, ad, threads)
+	if err != nil {
+		return err
+	}
+
+	if c.Mode != ModeArgon2d && c.Mode != ModeArgon2i && c.Mode != ModeArgon2id {
+		return ErrIncorrectType
+	}
+
+	// Align memory size. Minimum memory_blocks = 8L blocks, where L is the number of lanes.
+	memoryBlocks := c.MemoryCost
+	if memoryBlocks < 2*syncPoints*c.Parallelism {
+		memoryBlocks = 2 * syncPoints * c.Parallelism
+	}
+
+	segmentLength := memoryBlocks / (c.Parallelism * syncPoints)
+	memoryBlocks = segmentLength * (c.Parallelism * syncPoints)
+
+	if threads > c.Parallelism {
+		threads = c.Parallelism
+	}
+
+	inst := instance{
+		version:       c.Version,
+		passes:        c.TimeCost,
+		memoryBlocks:  memoryBlocks,
+		segmentLength: segmentLength,
+		laneLength:    segmentLength * syncPoints,
+		lanes:         c.Parallelism,
+		threads:       threads,
+		mode:          c.Mode,
+	}
+
+	if memory != nil {
+		if uint64(len(memory)) < uint64(memoryBlocks)*blockSize {
+			return ErrMemoryAllocationError
+		}
+		inst.memory = unsafe.Slice((*block)(unsafe.Pointer(&memory[0])), memoryBlocks)
+	} else {
+		inst.memory = make([]block, memoryBlocks)
+	}
+
+	defer wipeBlocks(inst.memory)
+
+	var blockhash [prehashSeedLen]byte
+	initialHash(blockhash[:prehashDigestLen], c, len(out), pwd, salt, secret, ad)
+	inst.fillFirstBlocks(&blockhash)
+	wipeBytes(blockhash[:])
+
+	err = inst.fillMemoryBlocks(ctx)
+	if err != nil {
+		return err
+	}
+
+	inst.finalize(out)
+	return nil
+}
+
+// validateInputs mirrors validate_inputs() from core.c.
+func validateInputs(c *Config, out []byte, pwd []byte, salt []byte, secret []byte, ad []byte, threads uint32) error {
+	switch {
+	case len(out) == 0:
+		return ErrOutputPtrNull
+	case len(out) < minOutLength:
+		return ErrOutputTooShort
+	case uint64(len(out)) > maxOutLength:
+		return ErrOutputTooLong
+	case uint64(len(pwd)) > maxPwdLength:
+		return ErrPwdTooLong
+	case len(salt) < minSaltLength:
+		return ErrSaltTooShort
+	case uint64(len(salt)) > maxSaltLength:
+		return ErrSaltTooLong
+	case uint64(len(secret)) > maxSecretLength:
+		return ErrSecretTooLong
+	case uint64(len(ad)) > maxAdLength:
+		return ErrAdTooLong
+	case c.MemoryCost < minMemory:
+		return ErrMemoryTooLittle
+	case uint64(c.MemoryCost) > maxMemory:
+		return ErrMemoryTooMuch
+	case uint64(c.MemoryCost) < 8*uint64(c.Parallelism):
+		return ErrMemoryTooLittle
+	case c.TimeCost < minTime:
+		return ErrTimeTooSmall
+	case c.Parallelism < minLanes:
+		return ErrLanesTooFew
+	case c.Parallelism > maxLanes:
+		return ErrLanesTooMany
+	case threads < minThreads:
+		return ErrThreadsTooFew
+	case threads > maxThreads:
+		return ErrThreadsTooMany
+	}
+	return nil
+}
+
+// initialHash mirrors initial_hash() from core.c.
+func initialHash(blockhash []byte, c *Config, outlen int, pwd []byte, salt []byte, secret []byte, ad []byte) {
+	var d blake2b
+
+	d.init(prehashDigestLen)
+	d.updateUint32(c.Parallelism)
+	d.updateUint32(uint32(outlen))
+	d.updateUint32(c.MemoryCost)
+	d.updateUint32(c.TimeCost)
+	d.updateUint32(uint32(c.Version))
+	d.updateUint32(uint32(c.Mode))
+	d.updateUint32(uint32(len(pwd)))
+	d.update(pwd)
+	d.updateUint32(uint32(len(salt)))
+	d.update(salt)
+	d.updateUint32(uint32(len(secret)))
+	d.update(secret)
+	d.updateUint32(uint32(len(ad)))
+	d.update(ad)
+	d.final(blockhash)
+}
+
+// fillFirstBlocks mirrors fill_first_blocks() from core.c.
+func (inst *instance) fillFirstBlocks(blockhash *[prehashSeedLen]byte) {
+	var blockhashBytes [blockSize]byte
+
+	for l := uint32(0); l < inst.lanes; l++ {
+		for i := uint32(0); i < 2; i++ {
+			binary.LittleEndian.PutUint32(blockhash[prehashDigestLen:], i)
+			binary.LittleEndian.PutUint32(blockhash[prehashDigestLen+4:], l)
+			blake2bLong(blockhashBytes[:], blockhash[:])
+			loadBlock(&inst.memory[l*inst.laneLength+i], &blockhashBytes)
+		}
+	}
+
+	wipeBytes(blockhashBytes[:])
+}
+
+// fillMemoryBlocks mirrors fill_memory_blocks() from core.c.
+//
+// The lanes of each slice are filled using up to inst.threads goroutines.
+// ctx is checked at every synchronization point.
+func (inst *instance) fillMemoryBlocks(ctx context.Context) error {
+	done := ctx.Done()
+
+	for r := uint32(0); r < inst.passes; r++ {
+		for s := uint32(0); s < syncPoints; s++ {
+			if done != nil {
+				select {
+				case <-done:
+					return ctx.Err()
+				default:
+				}
+			}
+
+			if inst.threads <= 1 {
+				for l := uint32(0); l < inst.lanes; l++ {
+					inst.fillSegment(position{pass: r, lane: l, slice: s})
+				}
+				continue
+			}
+
+			var wg sync.WaitGroup
+			sem := make(chan struct{}, inst.threads)
+
+			for l := uint32(0); l < inst.lanes; l++ {
+				sem <- struct{}{}
+				wg.Add(1)
+
+				go func(pos position) {
+					defer wg.Done()
+					inst.fillSegment(pos)
+					<-sem
+				}(position{pass: r, lane: l, slice: s})
+			}
+
+			wg.Wait()
+		}
+	}
+
+	return nil
+}
+
+// finalize mirrors finalize() from core.c, without freeing the memory.
+func (inst *instance) finalize(out []byte) {
+	var blockhashBytes [blockSize]byte
+	blockhash := inst.memory[inst.laneLength-1]
+
+	for l := uint32(1); l < inst.lanes; l++ {
+		xorBlock(&blockhash, &inst.memory[l*inst.laneLength+inst.laneLength-1])
+	}
+
+	storeBlock(&blockhashBytes, &blockhash)
+	blake2bLong(out, blockhashBytes[:])
+
+	wipeBlocks(unsafe.Slice(&blockhash, 1))
+	wipeBytes(blockhashBytes[:])
+}
+
+// indexAlpha mirrors index_alpha() from core.c.
+func (inst *instance) indexAlpha(pos *position, pseudoRand uint32, sameLane bool) uint32 {
+	var referenceAreaSize uint32
+
+	if pos.pass == 0 {
+		if pos.slice == 0 {
+			referenceAreaSize = pos.index - 1
+		} else if sameLane {
+			referenceAreaSize = pos.slice*inst.segmentLength + pos.index - 1
+		} else if pos.index == 0 {
+			referenceAreaSize = pos.slice*inst.segmentLength - 1
+		} else {
+			referenceAreaSize = pos.slice * inst.segmentLength
+		}
+	} else {
+		if sameLane {
+			referenceAreaSize = inst.laneLength - inst.segmentLength + pos.index - 1
+		} else if pos.index == 0 {
+			referenceAreaSize = inst.laneLength - inst.segmentLength - 1
+		} else {
+			referenceAreaSize = inst.laneLength - inst.segmentLength
+		}
+	}
+
+	relativePosition := uint64(pseudoRand)
+	relativePosition = relativePosition * relativePosition >> 32
+	relativePosition = uint64(referenceAreaSize) - 1 - (uint64(referenceAreaSize) * relativePosition >> 32)
+
+	startPosition := uint32(0)
+
+	if pos.pass != 0 && pos.slice != syncPoints-1 {
+		startPosition = (pos.slice + 1) * inst.segmentLength
+	}
+
+	return uint32((uint64(startPosition) + relativePosition) % uint64(inst.laneLength))
+}
+
+// fillSegment mirrors fill_segment() from ref_opt.c.
+func (inst *instance) fillSegment(pos position) {
+	var addressBlock, inputBlock, zeroBlock block
+
+	dataIndependentAddressing := inst.mode == ModeArgon2i ||
+		(inst.mode == ModeArgon2id && pos.pass == 0 && pos.slice < syncPoints/2)
+
+	if dataIndependentAddressing {
+		inputBlock[0] = uint64(pos.pass)
+		inputBlock[1] = uint64(pos.lane)
+		inputBlock[2] = uint64(pos.slice)
+		inputBlock[3] = uint64(inst.memoryBlocks)
+		inputBlock[4] = uint64(inst.passes)
+		inputBlock[5] = uint64(inst.mode)
+	}
+
+	startingIndex := uint32(0)
+
+	if pos.pass == 0 && pos.slice == 0 {
+		startingIndex = 2 // we have already generated the first two blocks
+
+		// Don't forget to generate the first block of addresses:
+		if dataIndependentAddressing {
+			nextAddresses(&addressBlock, &inputBlock, &zeroBlock)
+		}
+	}
+
+	// Offset of the current block
+	currOffset := pos.lane*inst.laneLength + pos.slice*inst.segmentLength + startingIndex
+	var prevOffset uint32
+
+	if currOffset%inst.laneLength == 0 {
+		// Last block in this lane
+		prevOffset = currOffset + inst.laneLength - 1
+	} else {
+		// Previous block
+		prevOffset = currOffset - 1
+	}
+
+	for i := startingIndex; i < inst.segmentLength; i, currOffset, prevOffset = i+1, currOffset+1, prevOffset+1 {
+		// 1.1 Rotating prevOffset if needed
+		if currOffset%inst.laneLength == 1 {
+			prevOffset = currOffset - 1
+		}
+
+		// 1.2 Computing the index of the reference block
+		// 1.2.1 Taking pseudo-random value from the previous block
+		var pseudoRand uint64
+
+		if dataIndependentAddressing {
+			if i%addressesInBlock == 0 {
+				nextAddresses(&addressBlock, &inputBlock, &zeroBlock)
+			}
+			pseudoRand = addressBlock[i%addressesInBlock]
+		} else {
+			pseudoRand = inst.memory[prevOffset][0]
+		}
+
+		// 1.2.2 Computing the lane of the reference block
+		refLane := uint32((pseudoRand >> 32) % uint64(inst.lanes))
+
+		if pos.pass == 0 && pos.slice == 0 {
+			// Can not reference other lanes yet
+			refLane = pos.lane
+		}
+
+		// 1.2.3 Computing the number of possible reference block within the lane.
+		pos.index = i
+		refIndex := inst.indexAlpha(&pos, uint32(pseudoRand), refLane == pos.lane)
+
+		// 2 Creating a new block
+		refBlock := &inst.memory[inst.laneLength*refLane+refIndex]
+		currBlock := &inst.memory[currOffset]
+		prevBlock := &inst.memory[prevOffset]
+
+		// Version 1.2.1 and earlier overwrite the block, instead of XORing it.
+		withXor := inst.version != Version10 && pos.pass != 0
+		fillBlock(prevBlock, refBlock, currBlock, withXor)
+	}
+}
+
+func nextAddresses(addressBlock *block, inputBlock *block, zeroBlock *block) {
+	inputBlock[6]++
+	fillBlock(zeroBlock, inputBlock, addressBlock, false)
+	fillBlock(zeroBlock, addressBlock, addressBlock, false)
+}
+
+// fillBlock mirrors the reference fill_block() from ref_opt.c.
+func fillBlock(prevBlock *block, refBlock *block, nextBlock *block, withXor bool) {
+	var blockR, blockTmp block
+
+	for i := range blockR {
+		blockR[i] = refBlock[i] ^ prevBlock[i]
+	}
+
+	blockTmp = blockR
+
+	if withXor {
+		xorBlock(&blockTmp, nextBlock)
+	}
+
+	// Apply Blake2 on columns of 64-bit words: (0,1,...,15), then
+	// (16,17,..31)... finally (112,113,...127)
+	for i := 0; i < 8; i++ {
+		blamkaRound(&blockR,
+			16*i, 16*i+1, 16*i+2, 16*i+3, 16*i+4, 16*i+5, 16*i+6, 16*i+7,
+			16*i+8, 16*i+9, 16*i+10, 16*i+11, 16*i+12, 16*i+13, 16*i+14, 16*i+15)
+	}
+
+	// Apply Blake2 on rows of 64-bit words: (0,1,16,17,...112,113), then
+	// (2,3,18,19,...,114,115).. finally (14,15,30,31,...,126,127)
+	for i := 0; i < 8; i++ {
+		blamkaRound(&blockR,
+			2*i, 2*i+1, 2*i+16, 2*i+17, 2*i+32, 2*i+33, 2*i+48, 2*i+49,
+			2*i+64, 2*i+65, 2*i+80, 2*i+81, 2*i+96, 2*i+97, 2*i+112, 2*i+113)
+	}
+
+	for i := range nextBlock {
+		nextBlock[i] = blockTmp[i] ^ blockR[i]
+	}
+}
+
+// blamkaRound mirrors BLAKE2_ROUND_NOMSG() from blamka-round-ref.h.
+func blamkaRound(b *block, v0, v1, v2, v3, v4, v5, v6, v7, v8, v9, v10, v11, v12, v13, v14, v15 int) {
+	blamkaG(b, v0, v4, v8, v12)
+	blamkaG(b, v1, v5, v9, v13)
+	blamkaG(b, v2, v6, v10, v14)
+	blamkaG(b, v3, v7, v11, v15)
+	blamkaG(b, v0, v5, v10, v15)
+	blamkaG(b, v1, v6, v11, v12)
+	blamkaG(b, v2, v7, v8, v13)
+	blamkaG(b, v3, v4, v9, v14)
+}
+
+func blamkaG(v *block, a, b, c, d int) {
+	v[a] = fBlaMka(v[a], v[b])
+	v[d] = bits.RotateLeft64(v[d]^v[a], -32)
+	v[c] = fBlaMka(v[c], v[d])
+	v[b] = bits.RotateLeft64(v[b]^v[c], -24)
+	v[a] = fBlaMka(v[a], v[b])
+	v[d] = bits.RotateLeft64(v[d]^v[a], -16)
+	v[c] = fBlaMka(v[c], v[d])
+	v[b] = bits.RotateLeft64(v[b]^v[c], -63)
+}
+
+func fBlaMka(x, y uint64) uint64 {
+	const m = 0xFFFFFFFF
+	return x + y + 2*((x&m)*(y&m))
+}
+
+func xorBlock(dst *block, src *block) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+func loadBlock(dst *block, src *[blockSize]byte) {
+	for i := range dst {
+		dst[i] = binary.LittleEndian.Uint64(src[8*i:])
+	}
+}
+
+func storeBlock(dst *[blockSize]byte, src *block) {
+	for i, v := range src {
+		binary.LittleEndian.PutUint64(dst[8*i:], v)
+	}
+}
+
+// wipeBlocks and wipeBytes mirror clear_internal_memory().
+func wipeBlocks(b []block) {
+	for i := range b {
+		b[i] = block{}
+	}
+}
+
+func wipeBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+)
+
+// Generated using the reference C implementation.
+var goldenHashes = []struct {
+	mode     Mode
+	version  Version
+	expected string
+}{
+	{ModeArgon2d, Version10, "21ef12847f23b2644236ffd0cfbcf57d43e0e23921da7f4e79d34a52260545a787d1768afb24898518a8dfc2c694cd28c364ab2e27d148d17d3647f937bcb34a6849fbb6e021027c0251385f52660e10"},
+	{ModeArgon2i, Version10, "5e1062e95e95b9120fb322870247629e2c640b26b022e2d9d30312f52b2d3d8ca44373b12a2cea987f6b5c5589c61b4eec3766500cd643a9561bf0f43d83c52e073af49973fe14374712220544f1be4b"},
+	{ModeArgon2id, Version10, "4792b3ca5f4ec8f71d7e73a34709929234702952b4e96170d416005aaa0b5c17481ecfa3efd8dc2552dabce1569cf3ea582826cce197974a33cf96a2329ef96f88814c16ea03ffc7d7c4d1cf25b69625"},
+	{ModeArgon2d, Version13, "736b4294d3cd8faa14771c602fe0fc877ff4f3d7991d8ae27c4d9beb86ad56d15f1240bd0cddeacb15aa64be6dade65147cbf142cbf343616de77ca69a5a64a0ca2e6099d97deb1958551ec39dca103e"},
+	{ModeArgon2i, Version13, "8dd594d853b09e03d067e04fd4b11df67bd0294b7d4a860d26dc7bfa8a1811d2f7c1628f91d62448eb58b718bcd002c82bfde03df08a3bb1e2bcaa2c14363564a5170570b21c00db44d2353033bc5814"},
+	{ModeArgon2id, Version13, "bd9fa0673ceed41c348c07cf187ec811825a07506397ec5c580eaa2eec02ac515120af8a39b81db5f982e78933193d355da59632f0b26961c2a775b20fd580a1f60eae453ac9bd7fbf566ecd5f8cfb46"},
+}
+
+func TestGoldenHashes(t *testing.T) {
+	for _, tc := range goldenHashes {
+		cfg := Config{HashLength: 80, TimeCost: 2, MemoryCost: 64, Parallelism: 2, Mode: tc.mode, Version: tc.version}
+
+		r, err := cfg.HashWithData(password, salt, []byte("secret"), []byte("data"))
+		mustBeFalsey(t, "err", err)
+
+		if hex.EncodeToString(r.Hash) != tc.expected {
+			t.Errorf("%s v=%s: hashes do not match", tc.mode, tc.version)
+		}
+	}
+}
+
+// Ensures that the pure Go implementation matches the one in use (which is the C one if cgo is enabled).
+func TestComputeGo(t *testing.T) {
+	for _, mode := range []Mode{ModeArgon2d, ModeArgon2i, ModeArgon2id} {
+		for _, version := range []Version{Version10, Version13} {
+			for _, p := range []uint32{1, 3} {
+				for _, m := range []uint32{8 * p, 100, 1000} {
+					cfg := Config{TimeCost: 3, MemoryCost: m, Parallelism: p, Mode: mode, Version: version}
+
+					for _, hashLength := range []int{4, 32, 64, 65, 200} {
+						expected := make([]byte, hashLength)
+						actual := make([]byte, hashLength)
+
+						err1 := compute(context.Background(), &cfg, expected, password, salt, nil, []byte("data"), nil)
+						err2 := computeGo(context.Background(), &cfg, actual, password, salt, nil, []byte("data"), nil)
+						mustBeFalsey(t, "err1", err1)
+						mustBeFalsey(t, "err2", err2)
+
+						if !bytes.Equal(expected, actual) {
+							t.Errorf("%s v=%s m=%d p=%d len=%d: hashes do not match", mode, version, m, p, hashLength)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestComputeGoErrors(t *testing.T) {
+	for _, tc := range []struct {
+		cfg      Config
+		salt     []byte
+		expected error
+	}{
+		{Config{HashLength: 0, TimeCost: 1, MemoryCost: 8, Parallelism: 1}, salt, ErrOutputPtrNull},
+		{Config{HashLength: 3, TimeCost: 1, MemoryCost: 8, Parallelism: 1}, salt, ErrOutputTooShort},
+		{Config{HashLength: 32, TimeCost: 1, MemoryCost: 8, Parallelism: 1}, []byte("salt"), ErrSaltTooShort},
+		{Config{HashLength: 32, TimeCost: 1, MemoryCost: 7, Parallelism: 1}, salt, ErrMemoryTooLittle},
+		{Config{HashLength: 32, TimeCost: 1, MemoryCost: 8, Parallelism: 2}, salt, ErrMemoryTooLittle},
+		{Config{HashLength: 32, TimeCost: 0, MemoryCost: 8, Parallelism: 1}, salt, ErrTimeTooSmall},
+		{Config{HashLength: 32, TimeCost: 1, MemoryCost: 8, Parallelism: 0}, salt, ErrLanesTooFew},
+		{Config{HashLength: 32, TimeCost: 1, MemoryCost: 8, Parallelism: 1, Mode: 3}, salt, ErrIncorrectType},
+	} {
+		out := make([]byte, tc.cfg.HashLength)
+
+		err1 := compute(context.Background(), &tc.cfg, out, password, tc.salt, nil, nil, nil)
+		err2 := computeGo(context.Background(), &tc.cfg, out, password, tc.salt, nil, nil, nil)
+
+		if err1 != tc.expected || err2 != tc.expected {
+			t.Errorf("expected %v, got: %v and %v", tc.expected, err1, err2)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"unsafe"
+)
+
+// stringBytes returns the bytes of `s` without copying them.
+// The result MUST NOT be modified.
+func stringBytes(s string) []byte {
+	if len(s) == 0 {
+		// Hash*() treat a nil password as missing.
+		return []byte{}
+	}
+	// The first word of a string header is the pointer to its data.
+	return unsafe.Slice(*(**byte)(unsafe.Pointer(&s)), len(s))
+}
+
+// stringConfig returns `c` without FlagClearPassword, which
+// would otherwise erase the read-only memory of a string.
+func (c *Config) stringConfig() *Config {
+	if c.Flags&FlagClearPassword == 0 {
+		return c
+	}
+	cc := *c
+	cc.Flags &^= FlagClearPassword
+	return &cc
+}
+
+// HashString works like Hash(), but takes the password as a string without copying it.
+func (c *Config) HashString(pwd string, salt []byte) (*Raw, error) {
+	return c.stringConfig().Hash(stringBytes(pwd), salt)
+}
+
+// HashIntoString works like HashInto(), but takes the password as a string without copying it.
+func (c *Config) HashIntoString(dst []byte, pwd string, salt []byte) error {
+	return c.stringConfig().HashInto(dst, stringBytes(pwd), salt)
+}
+
+// HashEncodedString works like HashEncoded(), but takes the password as a string without copying it.
+func (c *Config) HashEncodedString(pwd string) ([]byte, error) {
+	return c.stringConfig().HashEncoded(stringBytes(pwd))
+}
+
+// VerifyString works like Verify(), but takes the password as a string without copying it.
+func (raw *Raw) VerifyString(pwd string) (bool, error) {
+	r := *raw
+	r.Config = *raw.Config.stringConfig()
+	return r.Verify(stringBytes(pwd))
+}
+
+// VerifyEncodedString works like VerifyEncoded(), but takes the password
+// and encoded hash as strings without copying them.
+func VerifyEncodedString(pwd string, encoded string) (bool, error) {
+	return VerifyEncoded(stringBytes(pwd), stringBytes(encoded))
+}
@@ -1,3 +1,5 @@
+//go:build cgo
+
 /*
  * Argon2 reference source code package - reference C implementations
  *
@@ -0,0 +1,222 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FieldError describes a Config field with an invalid value. See Config.Validate().
+type FieldError struct {
+	// Field is the name of the Config field, e.g. "MemoryCost".
+	Field string
+
+	// Value is the invalid value of the field.
+	Value uint64
+
+	// Constraint describes the valid values, e.g. ">= 16".
+	Constraint string
+
+	// Err is the Error argon2 returns for this value, e.g. ErrMemoryTooLittle.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("argon2: Config.%s is %d, but must be %s", e.Field, e.Value, e.Constraint)
+}
+
+// Unwrap returns e.Err, which allows using errors.Is(err, ErrMemoryTooLittle) etc.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned by Config.Validate() and contains one FieldError per invalid field.
+type ValidationError []*FieldError
+
+// Error implements the error interface.
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns all FieldErrors, which allows using errors.As() and errors.Is() on them.
+func (e ValidationError) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, fe := range e {
+		errs[i] = fe
+	}
+	return errs
+}
+
+// Validate checks whether `c` can be used to compute hashes and returns
+// a ValidationError describing every invalid field if it can't.
+//
+// Unlike the Error returned by the Hash*() methods, this names the
+// offending fields and their limits, which allows you to check a Config
+// during startup instead of failing on the first hash.
+func (c *Config) Validate() error {
+	var errs ValidationError
+
+	add := func(field string, value uint64, constraint string, err error) {
+		errs = append(errs, &FieldError{Field: field, Value: value, Constraint: constraint, Err: err})
+	}
+
+	if c.HashLength < minOutLength {
+		add("HashLength", uint64(c.HashLength), fmt.Sprintf(">= %d", minOutLength), ErrOutputTooShort)
+	}
+
+	if c.SaltLength < minSaltLength {
+		add("SaltLength", uint64(c.SaltLength), fmt.Sprintf(">= %d", minSaltLength), ErrSaltTooShort)
+	}
+
+	if c.TimeCost < minTime {
+		add("TimeCost", uint64(c.TimeCost), fmt.Sprintf(">= %d", minTime), ErrTimeTooSmall)
+	}
+
+	if c.Parallelism < minLanes {
+		add("Parallelism", uint64(c.Parallelism), fmt.Sprintf(">= %d", minLanes), ErrLanesTooFew)
+	} else if c.Parallelism > maxLanes {
+		add("Parallelism", uint64(c.Parallelism), fmt.Sprintf("<= %d", maxLanes), ErrLanesTooMany)
+	}
+
+	lowestMemory := uint64(minMemory)
+	if m := uint64(minMemory) * uint64(c.Parallelism); m > lowestMemory {
+		lowestMemory = m
+	}
+
+	if uint64(c.MemoryCost) < lowestMemory {
+		add("MemoryCost", uint64(c.MemoryCost), fmt.Sprintf(">= %d (%d KiB per lane)", lowestMemory, minMemory), ErrMemoryTooLittle)
+	} else if uint64(c.MemoryCost) > maxMemory {
+		add("MemoryCost", uint64(c.MemoryCost), fmt.Sprintf("<= %d", uint64(maxMemory)), ErrMemoryTooMuch)
+	}
+
+	if c.Mode != ModeArgon2d && c.Mode != ModeArgon2i && c.Mode != ModeArgon2id {
+		add("Mode", uint64(c.Mode), "one of ModeArgon2d, ModeArgon2i or ModeArgon2id", ErrIncorrectType)
+	}
+
+	if c.Version != Version10 && c.Version != Version13 {
+		add("Version", uint64(c.Version), "one of Version10 or Version13", ErrIncorrectParameter)
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// Severity ranks the Findings returned by Config.Audit().
+type Severity int
+
+const (
+	// SeverityInfo denotes a setting which deviates from current recommendations,
+	// but is considered secure nonetheless.
+	SeverityInfo Severity = 0
+
+	// SeverityWarning denotes a setting which weakens the hash considerably.
+	SeverityWarning Severity = 1
+
+	// SeverityCritical denotes an invalid or insecure setting.
+	SeverityCritical Severity = 2
+)
+
+// String maps a Severity constant to a "{info,warning,critical}" string
+// or returns "unknown" if `s` does not match one of the constants.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// Finding describes a security issue found by Config.Audit().
+type Finding struct {
+	Severity Severity
+
+	// Field is the name of the Config field the finding relates to, e.g. "Mode".
+	Field string
+
+	// Message describes the issue.
+	Message string
+}
+
+// String returns the finding in the format "severity: Field: Message".
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.Field, f.Message)
+}
+
+// Audit returns the security findings for `c`, ordered by descending severity.
+//
+// Invalid fields (see Validate()) are reported as SeverityCritical.
+// The remaining findings are based on the recommendations of RFC 9106 and OWASP.
+// See RFC9106FirstConfig() and OWASP46MiBConfig() etc.
+//
+// To fail startup checks on weak settings, check whether any finding
+// has at least the Severity you're willing to accept.
+func (c *Config) Audit() []Finding {
+	var findings []Finding
+
+	add := func(severity Severity, field string, msg string) {
+		findings = append(findings, Finding{Severity: severity, Field: field, Message: msg})
+	}
+
+	if errs, ok := c.Validate().(ValidationError); ok {
+		for _, e := range errs {
+			add(SeverityCritical, e.Field, fmt.Sprintf("%d is invalid, must be %s", e.Value, e.Constraint))
+		}
+	}
+
+	switch c.Mode {
+	case ModeArgon2d:
+		add(SeverityWarning, "Mode", "Argon2d is vulnerable to side-channel attacks and should not be used for password hashing on servers")
+	case ModeArgon2i:
+		if c.TimeCost < 3 {
+			add(SeverityCritical, "TimeCost", "Argon2i requires a TimeCost of at least 3 due to time-memory trade-off attacks")
+		}
+		add(SeverityInfo, "Mode", "RFC 9106 recommends Argon2id")
+	}
+
+	if c.Version == Version10 {
+		add(SeverityWarning, "Version", "version 0x10 is deprecated, use Version13")
+	}
+
+	// The weakest OWASP recommendation uses 7 MiB and a TimeCost of 5, all of
+	// them require roughly the same amount of memory times passes.
+	const owaspMemory = 7 * 1024
+	const owaspMemoryTime = 7 * 1024 * 5
+
+	if c.MemoryCost < owaspMemory {
+		add(SeverityWarning, "MemoryCost", fmt.Sprintf("%d KiB is less than the minimum of %d KiB recommended by OWASP", c.MemoryCost, owaspMemory))
+	} else if uint64(c.MemoryCost)*uint64(c.TimeCost) < owaspMemoryTime {
+		add(SeverityWarning, "TimeCost", fmt.Sprintf("a TimeCost of %d is too low for %d KiB of memory according to OWASP", c.TimeCost, c.MemoryCost))
+	}
+
+	if c.SaltLength >= minSaltLength && c.SaltLength < 16 {
+		add(SeverityWarning, "SaltLength", "RFC 9106 recommends a salt length of 16 bytes")
+	}
+
+	if c.HashLength >= minOutLength && c.HashLength < 16 {
+		add(SeverityWarning, "HashLength", "hashes shorter than 16 bytes are prone to collisions")
+	} else if c.HashLength >= 16 && c.HashLength < 32 {
+		add(SeverityInfo, "HashLength", "RFC 9106 recommends a hash length of 32 bytes")
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity > findings[j].Severity
+	})
+
+	return findings
+}
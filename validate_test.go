@@ -0,0 +1,91 @@
+// Copyright (c) 2016 Leonard Hecker
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	mustBeFalsey(t, "err", config.Validate())
+
+	for _, cfg := range []Config{RFC9106FirstConfig(), RFC9106SecondConfig(), OWASP7MiBConfig()} {
+		mustBeFalsey(t, "err", cfg.Validate())
+	}
+
+	cfg := Config{HashLength: 3, SaltLength: 16, TimeCost: 1, MemoryCost: 16, Parallelism: 4, Mode: 3, Version: Version13}
+	err := cfg.Validate()
+
+	var verr ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a ValidationError, got: %v", err)
+	}
+
+	expected := []struct {
+		field string
+		err   error
+	}{
+		{"HashLength", ErrOutputTooShort},
+		{"MemoryCost", ErrMemoryTooLittle},
+		{"Mode", ErrIncorrectType},
+	}
+
+	if len(verr) != len(expected) {
+		t.Fatalf("expected %d field errors, got: %v", len(expected), err)
+	}
+
+	for i, e := range expected {
+		if verr[i].Field != e.field || verr[i].Err != e.err {
+			t.Errorf("expected %s (%v), got: %v", e.field, e.err, verr[i])
+		}
+	}
+
+	if !errors.Is(err, ErrMemoryTooLittle) {
+		t.Error("errors.Is() must find the wrapped Error")
+	}
+
+	if msg := verr[1].Error(); msg != "argon2: Config.MemoryCost is 16, but must be >= 32 (8 KiB per lane)" {
+		t.Errorf("unexpected message: %s", msg)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	for _, cfg := range []Config{RFC9106FirstConfig(), RFC9106SecondConfig(), OWASP46MiBConfig(), OWASP7MiBConfig()} {
+		if findings := cfg.Audit(); len(findings) != 0 {
+			t.Errorf("expected no findings for %+v, got: %v", cfg, findings)
+		}
+	}
+
+	cfg := Config{HashLength: 32, SaltLength: 8, TimeCost: 1, MemoryCost: 64 * 1024, Parallelism: 1, Mode: ModeArgon2i, Version: Version10}
+	findings := cfg.Audit()
+
+	expected := []struct {
+		severity Severity
+		field    string
+	}{
+		{SeverityCritical, "TimeCost"},
+		{SeverityWarning, "Version"},
+		{SeverityWarning, "SaltLength"},
+		{SeverityInfo, "Mode"},
+	}
+
+	if len(findings) != len(expected) {
+		t.Fatalf("expected %d findings, got: %v", len(expected), findings)
+	}
+
+	for i, e := range expected {
+		if findings[i].Severity != e.severity || findings[i].Field != e.field {
+			t.Errorf("expected %s: %s, got: %s", e.severity, e.field, findings[i])
+		}
+	}
+
+	cfg = Config{HashLength: 32, SaltLength: 16, TimeCost: 1, MemoryCost: 4, Parallelism: 1, Mode: ModeArgon2d, Version: Version13}
+	findings = cfg.Audit()
+
+	if len(findings) == 0 || findings[0].Severity != SeverityCritical || findings[0].Field != "MemoryCost" {
+		t.Errorf("expected the invalid MemoryCost to be reported first, got: %v", findings)
+	}
+}